@@ -0,0 +1,76 @@
+package authority
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// the built-in meta permissions guarding the administration of authority itself
+const (
+	PermRolesManage       = "authority.roles.manage"
+	PermPermissionsManage = "authority.permissions.manage"
+	PermAssignmentsManage = "authority.assignments.manage"
+	PermAuditView         = "authority.audit.view"
+)
+
+var metaPermissions = map[string]string{
+	PermRolesManage:       "create, update and delete roles",
+	PermPermissionsManage: "create, update and delete permissions",
+	PermAssignmentsManage: "assign and revoke roles and permissions",
+	PermAuditView:         "view the audit log",
+}
+
+// InstallMetaPermissions stores the built-in meta permissions in the database
+// it's safe to call it on every startup
+func (a *Authority) InstallMetaPermissions() error {
+	if err := a.checkMutation(context.Background(), Mutation{Operation: OpInstallMetaPermissions}); err != nil {
+		return err
+	}
+
+	for name, desc := range metaPermissions {
+		if err := a.CreatePermission(name, desc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Authorize checks if the principal has the given meta permission
+// it returns ErrForbidden if the principal doesn't have it
+func (a *Authority) Authorize(principalID uuid.UUID, metaPerm string) error {
+	ok, err := a.CheckPermission(principalID, metaPerm)
+	if err != nil && !errors.Is(err, ErrPermissionNotFound) {
+		return err
+	}
+	if !ok {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// RequireMetaPermission returns a middleware guarding an admin handler with the given meta permission
+// the principal func resolves the calling principal from the request
+func (a *Authority) RequireMetaPermission(metaPerm string, principal func(r *http.Request) (uuid.UUID, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principalID, err := principal(r)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if err := a.Authorize(principalID, metaPerm); err != nil {
+				code := ErrorCodeOf(err)
+				http.Error(w, code.String(), code.HTTPStatus())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package authority_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestAuthorize(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	// not installed meta permissions are never granted
+	admin := uuid.New()
+	err := auth.Authorize(admin, authority.PermRolesManage)
+	if !errors.Is(err, authority.ErrForbidden) {
+		t.Error("expecting forbidden before installing meta permissions")
+	}
+
+	err = auth.InstallMetaPermissions()
+	if err != nil {
+		t.Error("unexpected error while installing meta permissions.", err)
+	}
+	auth.CreateRole("role-admin", "a description role")
+	auth.CreateRole("role-a", "a description role")
+	auth.AssignPermissions("role-admin", []string{authority.PermRolesManage})
+	auth.AssignRole(admin, "role-admin")
+
+	err = auth.Authorize(admin, authority.PermRolesManage)
+	if err != nil {
+		t.Error("unexpected error while authorizing admin.", err)
+	}
+	err = auth.Authorize(admin, authority.PermAuditView)
+	if !errors.Is(err, authority.ErrForbidden) {
+		t.Error("expecting forbidden for a meta permission not granted")
+	}
+
+	// global admins can administer every role
+	ok, _ := auth.CanAdministerRole(admin, "role-a")
+	if !ok {
+		t.Error("expecting a global admin to administer the role")
+	}
+
+	// guarded handler
+	principal := uuid.New()
+	guard := auth.RequireMetaPermission(authority.PermRolesManage, func(r *http.Request) (uuid.UUID, error) {
+		return principal, nil
+	})
+	h := guard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/roles", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Error("expecting forbidden status for a principal without the meta permission")
+	}
+	principal = admin
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/roles", nil))
+	if rec.Code != http.StatusOK {
+		t.Error("expecting the admin to reach the handler")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-admin").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name LIKE ?", "authority.%").Delete(authority.Permission{})
+	db.Where("name IN (?)", []string{"role-admin", "role-a"}).Delete(authority.Role{})
+}
@@ -0,0 +1,187 @@
+package authority
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AnomalyRule flags suspicious assignment events
+type AnomalyRule interface {
+	// Name identifies the rule in the reported anomalies
+	Name() string
+	// Check returns the reason if the event is suspicious
+	Check(ev AssignmentEvent) (reason string, suspicious bool)
+}
+
+// Anomaly is a suspicious assignment event flagged by a rule
+type Anomaly struct {
+	Rule   string          `json:"rule"`
+	Reason string          `json:"reason"`
+	Event  AssignmentEvent `json:"event"`
+}
+
+// AddAnomalyRule adds a rule checked against every recorded assignment event
+func (a *Authority) AddAnomalyRule(rule AnomalyRule) {
+	a.anomalyMu.Lock()
+	defer a.anomalyMu.Unlock()
+	a.anomalyRules = append(a.anomalyRules, rule)
+}
+
+// SetAnomalyHandler sets the func invoked with every flagged event
+// it's called synchronously by the mutation so it should not block
+func (a *Authority) SetAnomalyHandler(handler func(Anomaly)) {
+	a.anomalyMu.Lock()
+	defer a.anomalyMu.Unlock()
+	a.anomalyHandler = handler
+}
+
+// detectAnomalies checks the events against the rules and reports the flagged ones
+func (a *Authority) detectAnomalies(events []AssignmentEvent) {
+	a.anomalyMu.RLock()
+	rules, handler := a.anomalyRules, a.anomalyHandler
+	a.anomalyMu.RUnlock()
+	if handler == nil {
+		return
+	}
+
+	for _, ev := range events {
+		for _, rule := range rules {
+			if reason, suspicious := rule.Check(ev); suspicious {
+				handler(Anomaly{Rule: rule.Name(), Reason: reason, Event: ev})
+			}
+		}
+	}
+}
+
+// businessHoursRule flags the assignments of sensitive roles outside business hours
+type businessHoursRule struct {
+	roles      map[string]bool
+	start, end int
+	loc        *time.Location
+}
+
+// NewBusinessHoursRule returns a rule flagging the assignments of the given roles
+// made outside the business hours, from the start hour included to the end hour excluded
+// in the given location, and during the weekends
+func NewBusinessHoursRule(roleNames []string, start, end int, loc *time.Location) AnomalyRule {
+	r := &businessHoursRule{roles: map[string]bool{}, start: start, end: end, loc: loc}
+	for _, name := range roleNames {
+		r.roles[name] = true
+	}
+
+	return r
+}
+
+func (r *businessHoursRule) Name() string {
+	return "business_hours"
+}
+
+func (r *businessHoursRule) Check(ev AssignmentEvent) (string, bool) {
+	if ev.Action != EventRoleAssigned || !r.roles[ev.RoleName] {
+		return "", false
+	}
+
+	at := ev.CreatedAt.In(r.loc)
+	weekend := at.Weekday() == time.Saturday || at.Weekday() == time.Sunday
+	if weekend || at.Hour() < r.start || at.Hour() >= r.end {
+		return fmt.Sprintf("role %s assigned outside business hours at %s", ev.RoleName, at.Format(time.RFC3339)), true
+	}
+
+	return "", false
+}
+
+// rateRule flags the bursts of events of an action
+type rateRule struct {
+	action string
+	limit  int
+	window time.Duration
+
+	mu    sync.Mutex
+	times []time.Time
+}
+
+// NewRateRule returns a rule flagging the events of the given action once more than
+// the limit are recorded by this instance within the window
+func NewRateRule(action string, limit int, window time.Duration) AnomalyRule {
+	return &rateRule{action: action, limit: limit, window: window}
+}
+
+func (r *rateRule) Name() string {
+	return "rate"
+}
+
+func (r *rateRule) Check(ev AssignmentEvent) (string, bool) {
+	if ev.Action != r.action {
+		return "", false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// drop the events out of the window
+	since := ev.CreatedAt.Add(-r.window)
+	i := 0
+	for i < len(r.times) && !r.times[i].After(since) {
+		i++
+	}
+	r.times = append(r.times[i:], ev.CreatedAt)
+
+	if len(r.times) > r.limit {
+		return fmt.Sprintf("%d %s events within %s", len(r.times), r.action, r.window), true
+	}
+
+	return "", false
+}
+
+// AnomalyWebhookOptions has the options of an anomaly webhook
+type AnomalyWebhookOptions struct {
+	// URL receives the anomalies as json posts
+	URL string
+	// Timeout bounds every post, defaults to 5 seconds
+	Timeout time.Duration
+	// HTTPClient overrides the default http client
+	HTTPClient *http.Client
+	// OnError is called with the failed posts if set
+	OnError func(Anomaly, error)
+}
+
+// NewAnomalyWebhook returns an anomaly handler posting the anomalies to a webhook
+// the posts are sent in the background so they don't block the mutations
+func NewAnomalyWebhook(opts AnomalyWebhookOptions) func(Anomaly) {
+	client := opts.HTTPClient
+	if client == nil {
+		timeout := opts.Timeout
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	post := func(an Anomaly) error {
+		body, err := json.Marshal(an)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Post(opts.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	return func(an Anomaly) {
+		go func() {
+			if err := post(an); err != nil && opts.OnError != nil {
+				opts.OnError(an, err)
+			}
+		}()
+	}
+}
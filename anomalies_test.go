@@ -0,0 +1,133 @@
+package authority_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestAnomalyRules(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	var mu sync.Mutex
+	var anomalies []authority.Anomaly
+	auth.SetAnomalyHandler(func(an authority.Anomaly) {
+		mu.Lock()
+		anomalies = append(anomalies, an)
+		mu.Unlock()
+	})
+	// no business hours at all
+	auth.AddAnomalyRule(authority.NewBusinessHoursRule([]string{"role-b"}, 0, 0, time.UTC))
+	auth.AddAnomalyRule(authority.NewRateRule(authority.EventRoleAssigned, 2, time.Minute))
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreateRole("role-b", "b description role")
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		auth.AssignRole(id, "role-a")
+	}
+	if len(anomalies) != 1 || anomalies[0].Rule != "rate" {
+		t.Errorf("expecting the third assignment to be flagged, got %+v", anomalies)
+	}
+
+	anomalies = nil
+	auth.RevokeRole(ids[0], "role-a")
+	auth.SetAnomalyHandler(nil)
+	auth.AssignRole(ids[1], "role-b")
+	if len(anomalies) != 0 {
+		t.Error("expecting no anomalies without a handler")
+	}
+
+	// clean up
+	db.Where("user_id IN (?)", ids).Delete(authority.AssignmentEvent{})
+	db.Where("user_id IN (?)", ids).Delete(authority.UserRole{})
+	db.Where("name IN (?)", []string{"role-a", "role-b"}).Delete(authority.Role{})
+}
+
+func TestAnomalyWebhook(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	received := make(chan authority.Anomaly, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var an authority.Anomaly
+		json.NewDecoder(r.Body).Decode(&an)
+		received <- an
+	}))
+	defer server.Close()
+
+	auth.SetAnomalyHandler(authority.NewAnomalyWebhook(authority.AnomalyWebhookOptions{URL: server.URL}))
+	auth.AddAnomalyRule(authority.NewBusinessHoursRule([]string{"role-a"}, 0, 0, time.UTC))
+
+	auth.CreateRole("role-a", "a description role")
+	id := uuid.New()
+	auth.AssignRole(id, "role-a")
+
+	select {
+	case an := <-received:
+		if an.Rule != "business_hours" || an.Event.UserID != id {
+			t.Errorf("unexpected anomaly %+v", an)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("expecting the anomaly to be posted")
+	}
+
+	// clean up
+	db.Where("user_id = ?", id).Delete(authority.AssignmentEvent{})
+	db.Where("user_id = ?", id).Delete(authority.UserRole{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestAnomalyRollback(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	var anomalies []authority.Anomaly
+	auth.SetAnomalyHandler(func(an authority.Anomaly) {
+		anomalies = append(anomalies, an)
+	})
+	auth.AddAnomalyRule(authority.NewRateRule(authority.EventPermissionAssigned, 0, time.Minute))
+
+	// the grant of the first role is rolled back with the conflict of the second one
+	auth.CreateRole("role-b", "an application role")
+	perms := []authority.ModulePermission{{Name: "audit.view"}}
+	err := auth.RegisterModule("audit", []authority.ModuleRole{
+		{Name: "audit-admin", Permissions: []string{"audit.view"}},
+		{Name: "role-b"},
+	}, perms)
+	if err == nil {
+		t.Error("expecting an error when installing a role of the application")
+	}
+	if len(anomalies) != 0 {
+		t.Errorf("expecting no anomalies for a rolled back grant, got %+v", anomalies)
+	}
+
+	err = auth.RegisterModule("audit", []authority.ModuleRole{{Name: "audit-admin", Permissions: []string{"audit.view"}}}, perms)
+	if err != nil {
+		t.Error("unexpected error while registering module.", err)
+	}
+	if len(anomalies) != 1 {
+		t.Errorf("expecting the committed grant to be flagged, got %+v", anomalies)
+	}
+
+	// clean up
+	auth.RemoveModule("audit")
+	auth.DeleteRole("role-b")
+	db.Where("role_name IN (?)", []string{"audit-admin", "role-b"}).Delete(authority.AssignmentEvent{})
+	db.Where("permission_name = ?", "audit.view").Delete(authority.AssignmentEvent{})
+}
@@ -0,0 +1,45 @@
+package authority
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the actions of the assignment events
+const (
+	EventRoleAssigned       = "role_assigned"
+	EventRoleRevoked        = "role_revoked"
+	EventPermissionAssigned = "permission_assigned"
+	EventPermissionRevoked  = "permission_revoked"
+	EventRoleDeleted        = "role_deleted"
+	EventPermissionDeleted  = "permission_deleted"
+)
+
+// AssignmentEvent represents the database model of the history of the assignments
+// the names are the ones at the time of the event so deleted roles and permissions
+// can still be reported
+type AssignmentEvent struct {
+	ID             uint
+	Action         string
+	UserID         uuid.UUID
+	TenantID       string `gorm:"size:191;not null;default:''"`
+	RoleID         uint
+	RoleName       string
+	PermissionID   uint
+	PermissionName string
+	// Reason and TicketRef are the justification of the assignment, encrypted if an encryptor is set
+	Reason    string
+	TicketRef string
+	CreatedAt time.Time
+}
+
+// TableName sets the table name
+func (e AssignmentEvent) TableName() string {
+	return defaultPrefix() + e.table()
+}
+
+// table returns the table name without the prefix
+func (AssignmentEvent) table() string {
+	return "assignment_events"
+}
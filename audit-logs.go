@@ -0,0 +1,33 @@
+package authority
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the actions recorded in the audit log
+const (
+	AuditCheckPermissionAs = "check_permission_as"
+)
+
+// AuditLog represents the database model of audit entries
+type AuditLog struct {
+	ID         uint
+	Action     string
+	ActorID    uuid.UUID
+	SubjectID  uuid.UUID
+	Permission string
+	Allowed    bool
+	CreatedAt  time.Time
+}
+
+// TableName sets the table name
+func (l AuditLog) TableName() string {
+	return defaultPrefix() + l.table()
+}
+
+// table returns the table name without the prefix
+func (AuditLog) table() string {
+	return "audit_logs"
+}
@@ -1,83 +1,219 @@
 package authority
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Authority helps deal with permissions
 type Authority struct {
 	DB *gorm.DB
+
+	fedMu      sync.RWMutex
+	federation map[string]*FederationClient
+
+	instanceID string
+	cache      *checkCache
+
+	notifierMu sync.RWMutex
+	notifier   CacheNotifier
+
+	anomalyMu      sync.RWMutex
+	anomalyRules   []AnomalyRule
+	anomalyHandler func(Anomaly)
+
+	frozen   int32
+	readOnly bool
+	// duplicateNames is set when the unique indexes of the names are missing
+	duplicateNames bool
+
+	hookMu       sync.RWMutex
+	mutationHook MutationHook
+
+	logger Logger
+
+	encMu     sync.RWMutex
+	encryptor Encryptor
+
+	clock Clock
+
+	usage *usageCounters
+
+	tables *tables
 }
 
 // Options has the options for initiating the package
 type Options struct {
 	TablesPrefix string
 	DB           *gorm.DB
+	// CacheTTL enables caching the user checks for the given duration
+	CacheTTL time.Duration
+	// Logger receives the errors that cannot be returned, they are dropped if nil
+	Logger Logger
+	// Clock is the time source, defaults to the system time
+	Clock Clock
+	// ReadOnly rejects all the mutations and skips the migrations, it's meant
+	// for the check only deployments that must never modify the policy
+	ReadOnly bool
+	// TrackUsage counts the checks of every permission, see GetPermissionUsage
+	TrackUsage bool
+	// MergeDuplicateNames merges the roles and the permissions sharing a name before
+	// migrating, it's needed once to create the unique indexes of the names on a database
+	// storing duplicated names
+	MergeDuplicateNames bool
 }
 
+// auth is the default instance, the last instance initiated successfully
+var auth *Authority
+
 var (
-	ErrPermissionInUse     = errors.New("cannot delete assigned permission")
-	ErrPermissionNotFound  = errors.New("permission not found")
-	ErrRoleAlreadyAssigned = errors.New("this role is already assigned to the user")
-	ErrRoleInUse           = errors.New("cannot delete assigned role")
-	ErrRoleNotFound        = errors.New("role not found")
+	registryMu sync.RWMutex
+	registry   = map[string]*Authority{}
 )
 
-var tablePrefix string
-
-var auth *Authority
-
 // New initiates authority
+// the migration errors are passed to the logger, use NewWithError to get them
+// the roles and the permissions cannot be created if the unique indexes of their names
+// could not be created, see Options.MergeDuplicateNames
 func New(opts Options) *Authority {
-	tablePrefix = opts.TablesPrefix
-	auth = &Authority{
-		DB: opts.DB,
+	a := newAuthority(opts)
+	if !opts.ReadOnly {
+		if err := a.migrateTables(opts.MergeDuplicateNames); err != nil {
+			a.logf("authority: %v", err)
+		}
 	}
+	setDefault(a)
 
-	migrateTables(opts.DB)
-	return auth
+	return a
+}
+
+// NewWithError initiates authority like New
+// it returns an error if the options are not valid, the database could not be reached
+// or the tables could not be migrated, ErrDuplicateNames if the unique indexes of the names
+// could not be created
+func NewWithError(opts Options) (*Authority, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	sqlDB, err := opts.DB.DB()
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return nil, storeError(err)
+	}
+
+	a := newAuthority(opts)
+	if !opts.ReadOnly {
+		if err := a.migrateTables(opts.MergeDuplicateNames); err != nil {
+			return nil, err
+		}
+	}
+	setDefault(a)
+
+	return a, nil
+}
+
+// newAuthority initiates authority without migrating the tables
+// the tables of the instance are named with its own prefix
+func newAuthority(opts Options) *Authority {
+	a := &Authority{
+		DB:         opts.DB,
+		instanceID: uuid.NewString(),
+		logger:     opts.Logger,
+		clock:      opts.Clock,
+		readOnly:   opts.ReadOnly,
+		tables:     &tables{prefix: opts.TablesPrefix},
+	}
+	if opts.DB != nil {
+		a.DB = scopeTables(opts.DB, a.tables)
+	}
+	if opts.Clock == nil {
+		a.clock = systemClock{}
+	} else if opts.DB != nil {
+		a.DB = withClock(a.DB, opts.Clock)
+	}
+	if opts.TrackUsage {
+		a.usage = &usageCounters{pending: map[string]*PermissionUsage{}}
+	}
+	if opts.CacheTTL > 0 {
+		a.cache = newCheckCache(opts.CacheTTL, a.clock)
+	}
+
+	return a
 }
 
 // Resolve returns the initiated instance
-func Resolve() *Authority {
+// if a name is passed it returns the instance registered with that name, nil if there is none
+func Resolve(name ...string) *Authority {
+	if len(name) > 0 {
+		registryMu.RLock()
+		defer registryMu.RUnlock()
+		return registry[name[0]]
+	}
+
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
 	return auth
 }
 
+// Register registers the instance under a name to be resolved with Resolve(name)
+// every instance keeps its own tables prefix
+func Register(name string, a *Authority) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = a
+}
+
 // CreateRole stores a role in the database
 // it accepts the role name. it returns an error
 // in case of any
 func (a *Authority) CreateRole(roleName string, description string) error {
-	var dbRole Role
-	res := a.DB.Where("name = ?", roleName).First(&dbRole)
-	if res.Error != nil {
-		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			// create
-			a.DB.Create(&Role{Name: roleName, Description: description})
-			return nil
-		}
+	return a.CreateRoleContext(context.Background(), roleName, description)
+}
+
+// CreateRoleContext stores a role on behalf of the actor of the context
+func (a *Authority) CreateRoleContext(ctx context.Context, roleName string, description string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpCreateRole, Role: roleName}); err != nil {
+		return err
 	}
+	if a.duplicateNames {
+		return ErrDuplicateNames
+	}
+
+	// the unique name makes concurrent creates of the same role safe
+	res := a.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&Role{Name: roleName, Description: description})
 
-	return res.Error
+	return storeError(res.Error)
 }
 
 // CreatePermission stores a permission in the database
 // it accepts the permission name. it returns an error
 // in case of any
 func (a *Authority) CreatePermission(permName string, desciption string) error {
-	var dbPerm Permission
-	res := a.DB.Where("name = ?", permName).First(&dbPerm)
-	if res.Error != nil {
-		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			// create
-			a.DB.Create(&Permission{Name: permName, Description: desciption})
-			return nil
-		}
+	return a.CreatePermissionContext(context.Background(), permName, desciption)
+}
+
+// CreatePermissionContext stores a permission on behalf of the actor of the context
+func (a *Authority) CreatePermissionContext(ctx context.Context, permName string, desciption string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpCreatePermission, Permissions: []string{permName}}); err != nil {
+		return err
+	}
+	if a.duplicateNames {
+		return ErrDuplicateNames
 	}
 
-	return res.Error
+	// the unique name makes concurrent creates of the same permission safe
+	res := a.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&Permission{Name: permName, Description: desciption})
+
+	return storeError(res.Error)
 }
 
 // AssignPermissions assigns a group of permissions to a given role
@@ -87,10 +223,26 @@ func (a *Authority) CreatePermission(permName string, desciption string) error {
 // if any of these permissions doesn't have a matching record in the database the operations stops, changes reverted
 // and error is returned
 // in case of success nothing is returned
-func (a *Authority) AssignPermissions(roleName string, permNames []string) error {
+// the options set the justification stored along with the assignments
+func (a *Authority) AssignPermissions(roleName string, permNames []string, opts ...AssignOption) error {
+	return a.AssignPermissionsContext(context.Background(), roleName, permNames, opts...)
+}
+
+// AssignPermissionsContext assigns a group of permissions to a role on behalf of the actor of the context
+func (a *Authority) AssignPermissionsContext(ctx context.Context, roleName string, permNames []string, opts ...AssignOption) error {
+	note := newNote(opts)
+	if err := a.checkMutation(ctx, Mutation{Operation: OpAssignPermissions, Role: roleName, Permissions: permNames, Note: note}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+	sealed, err := a.sealNote(note)
+	if err != nil {
+		return err
+	}
+
 	// get the role id
 	var role Role
-	rRes := a.DB.Where("name = ?", roleName).First(&role)
+	rRes := db.Where("name = ?", roleName).First(&role)
 	if rRes.Error != nil {
 		if errors.Is(rRes.Error, gorm.ErrRecordNotFound) {
 			return ErrRoleNotFound
@@ -102,7 +254,7 @@ func (a *Authority) AssignPermissions(roleName string, permNames []string) error
 	// get the permissions ids
 	for _, permName := range permNames {
 		var perm Permission
-		pRes := a.DB.Where("name = ?", permName).First(&perm)
+		pRes := db.Where("name = ?", permName).First(&perm)
 		if pRes.Error != nil {
 			if errors.Is(pRes.Error, gorm.ErrRecordNotFound) {
 				return ErrPermissionNotFound
@@ -117,21 +269,40 @@ func (a *Authority) AssignPermissions(roleName string, permNames []string) error
 	for _, perm := range perms {
 		// ignore any assigned permission
 		var rolePerm RolePermission
-		res := a.DB.Where("role_id = ?", role.ID).Where("permission_id =?", perm.ID).First(&rolePerm)
+		res := db.Where("role_id = ?", role.ID).Where("permission_id =?", perm.ID).First(&rolePerm)
 		if res.Error != nil {
-			// assign the record
-			cRes := a.DB.Create(&RolePermission{RoleID: role.ID, PermissionID: perm.ID})
-			if cRes.Error != nil {
-				return cRes.Error
+			// assign the record along with its event
+			err := a.transaction(db, func(tx *gorm.DB) error {
+				cRes := tx.Create(&RolePermission{RoleID: role.ID, PermissionID: perm.ID, Reason: sealed.Reason, TicketRef: sealed.TicketRef})
+				if cRes.Error != nil {
+					return storeError(cRes.Error)
+				}
+				ev := rolePermissionEvent(EventPermissionAssigned, role, perm)
+				ev.Reason, ev.TicketRef = sealed.Reason, sealed.TicketRef
+				return a.recordEvents(tx, ev)
+			})
+			if err != nil {
+				return err
 			}
 		}
 	}
+	a.invalidate(uuid.Nil)
 
 	return nil
 }
 
 func (a *Authority) SyncAssignPermissions(roleName string, permNames []string) error {
-	tx := a.DB.Session(&gorm.Session{SkipDefaultTransaction: true})
+	return a.SyncAssignPermissionsContext(context.Background(), roleName, permNames)
+}
+
+// SyncAssignPermissionsContext syncs the permissions of a role on behalf of the actor of the context
+func (a *Authority) SyncAssignPermissionsContext(ctx context.Context, roleName string, permNames []string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpSyncPermissions, Role: roleName, Permissions: permNames}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+
+	tx := db.Session(&gorm.Session{SkipDefaultTransaction: true})
 	// tx = a.DB.Begin()
 	// get the role id
 	var role Role
@@ -160,12 +331,29 @@ func (a *Authority) SyncAssignPermissions(roleName string, permNames []string) e
 		perms = append(perms, perm)
 	}
 
+	// record the revoke of the current permissions
+	var current []RolePermission
+	tx.Where("role_id = ?", role.ID).Find(&current)
+	var currentIDs []uint
+	for _, rp := range current {
+		currentIDs = append(currentIDs, rp.PermissionID)
+	}
+	currentPerms, err := permissionsByID(tx, currentIDs)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	var events []AssignmentEvent
+	for _, rp := range current {
+		events = append(events, rolePermissionEvent(EventPermissionRevoked, role, currentPerms[rp.PermissionID]))
+	}
+
 	//delete all rolespermission
 	delData := tx.Where("role_id = ?", role.ID).Delete(RolePermission{})
 
 	if delData.Error != nil {
 		tx.Rollback()
-		return delData.Error
+		return storeError(delData.Error)
 	}
 
 	for _, perm := range perms {
@@ -173,11 +361,17 @@ func (a *Authority) SyncAssignPermissions(roleName string, permNames []string) e
 		cRes := tx.Create(&RolePermission{RoleID: role.ID, PermissionID: perm.ID})
 		if cRes.Error != nil {
 			tx.Rollback()
-			return cRes.Error
+			return storeError(cRes.Error)
 		}
+		events = append(events, rolePermissionEvent(EventPermissionAssigned, role, perm))
+	}
+	if err := a.recordEvents(tx, events...); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	tx.Commit()
+	a.invalidate(uuid.Nil)
 
 	return nil
 }
@@ -186,7 +380,22 @@ func (a *Authority) SyncAssignPermissions(roleName string, permNames []string) e
 // the first parameter is the user id, the second parameter is the role name
 // if the role name doesn't have a matching record in the data base an error is returned
 // if the user have already a role assigned to him an error is returned
-func (a *Authority) AssignRole(userID uuid.UUID, roleName string) error {
+// the options set the justification stored along with the assignment
+func (a *Authority) AssignRole(userID uuid.UUID, roleName string, opts ...AssignOption) error {
+	return a.AssignRoleContext(context.Background(), userID, roleName, opts...)
+}
+
+// AssignRoleContext assigns a given role to a user within the tenant of the context
+func (a *Authority) AssignRoleContext(ctx context.Context, userID uuid.UUID, roleName string, opts ...AssignOption) error {
+	note := newNote(opts)
+	if err := a.checkMutation(ctx, Mutation{Operation: OpAssignRole, UserID: userID, Role: roleName, Note: note}); err != nil {
+		return err
+	}
+	sealed, err := a.sealNote(note)
+	if err != nil {
+		return err
+	}
+
 	// make sure the role exist
 	var role Role
 	res := a.DB.Where("name = ?", roleName).First(&role)
@@ -198,14 +407,26 @@ func (a *Authority) AssignRole(userID uuid.UUID, roleName string) error {
 
 	// check if the role is already assigned
 	var userRole UserRole
-	res = a.DB.Where("user_id = ?", userID).Where("role_id = ?", role.ID).First(&userRole)
+	res = a.userRoles(ctx).Where("user_id = ?", userID).Where("role_id = ?", role.ID).First(&userRole)
 	if res.Error == nil {
 		//found a record, this role is already assigned to the same user
 		return ErrRoleAlreadyAssigned
 	}
 
-	// assign the role
-	a.DB.Create(&UserRole{UserID: userID, RoleID: role.ID})
+	// assign the role along with its event
+	err = a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		cRes := tx.Create(&UserRole{UserID: userID, RoleID: role.ID, TenantID: TenantFromContext(ctx), Reason: sealed.Reason, TicketRef: sealed.TicketRef})
+		if cRes.Error != nil {
+			return storeError(cRes.Error)
+		}
+		ev := userRoleEvent(EventRoleAssigned, userID, TenantFromContext(ctx), role)
+		ev.Reason, ev.TicketRef = sealed.Reason, sealed.TicketRef
+		return a.recordEvents(tx, ev)
+	})
+	if err != nil {
+		return err
+	}
+	a.invalidate(userID)
 
 	return nil
 }
@@ -215,6 +436,16 @@ func (a *Authority) AssignRole(userID uuid.UUID, roleName string) error {
 // the role as the second parameter
 // it returns an error if the role is not present in database
 func (a *Authority) CheckRole(userID uuid.UUID, roleName string) (bool, error) {
+	return a.CheckRoleContext(context.Background(), userID, roleName)
+}
+
+// CheckRoleContext checks if a role is assigned to a user within the tenant of the context
+func (a *Authority) CheckRoleContext(ctx context.Context, userID uuid.UUID, roleName string) (bool, error) {
+	key := cacheKey(ctx, "role", roleName)
+	if ok, found := a.cache.get(userID, key); found {
+		return ok, nil
+	}
+
 	// find the role
 	var role Role
 	res := a.DB.Where("name = ?", roleName).First(&role)
@@ -227,14 +458,16 @@ func (a *Authority) CheckRole(userID uuid.UUID, roleName string) (bool, error) {
 
 	// check if the role is a assigned
 	var userRole UserRole
-	res = a.DB.Where("user_id = ?", userID).Where("role_id = ?", role.ID).First(&userRole)
+	res = a.userRoles(ctx).Where("user_id = ?", userID).Where("role_id = ?", role.ID).First(&userRole)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			a.cache.set(userID, key, false)
 			return false, nil
 		}
 
 	}
 
+	a.cache.set(userID, key, true)
 	return true, nil
 }
 
@@ -242,13 +475,48 @@ func (a *Authority) CheckRole(userID uuid.UUID, roleName string) (bool, error) {
 // it accepts the user id as the first parameter
 // the permission as the second parameter
 // it returns an error if the permission is not present in the database
+// permissions of a federated resource type are checked by the owning service
 func (a *Authority) CheckPermission(userID uuid.UUID, permName string) (bool, error) {
+	return a.CheckPermissionContext(context.Background(), userID, permName)
+}
+
+// CheckPermissionContext checks if a permission is assigned to the roles
+// that are assigned to the user within the tenant of the context
+func (a *Authority) CheckPermissionContext(ctx context.Context, userID uuid.UUID, permName string) (bool, error) {
+	var ok bool
+	var err error
+	if c := a.federationClient(permName); c != nil {
+		ok, err = c.CheckPermissionContext(ctx, userID, permName)
+	} else {
+		ok, err = a.checkLocalPermission(ctx, userID, permName)
+	}
+	a.countCheck(permName, ok, err)
+
+	return ok, err
+}
+
+// checkLocalPermission checks the permission against the local database
+func (a *Authority) checkLocalPermission(ctx context.Context, userID uuid.UUID, permName string) (bool, error) {
+	d, _, err := a.evaluateLocal(ctx, userID, permName)
+	return d.Allowed, err
+}
+
+// evaluateLocal checks the permission against the local store
+// the granting role permission is returned if the check is allowed and not cached
+func (a *Authority) evaluateLocal(ctx context.Context, userID uuid.UUID, permName string) (Decision, RolePermission, error) {
+	d := Decision{Permission: permName, Source: SourceLocal, EvaluatedAt: a.now()}
+	key := cacheKey(ctx, "permission", permName)
+	if ok, found := a.cache.get(userID, key); found {
+		d.Allowed, d.CacheHit = ok, true
+		return d, RolePermission{}, nil
+	}
+
 	// the user role
 	var userRoles []UserRole
-	res := a.DB.Where("user_id = ?", userID).Find(&userRoles)
+	res := a.userRoles(ctx).Where("user_id = ?", userID).Find(&userRoles)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return false, nil
+			return d, RolePermission{}, nil
 		}
 	}
 
@@ -263,19 +531,58 @@ func (a *Authority) CheckPermission(userID uuid.UUID, permName string) (bool, er
 	res = a.DB.Where("name = ?", permName).First(&perm)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return false, ErrPermissionNotFound
+			return d, RolePermission{}, ErrPermissionNotFound
 		}
 
 	}
+	d.Description = perm.Description
+
+	// the permission is granted by itself or any permission implying it
+	permIDs, err := a.impliersOf(perm.ID)
+	if err != nil {
+		return d, RolePermission{}, err
+	}
 
 	// find the role permission
 	var rolePermission RolePermission
-	res = a.DB.Where("role_id IN (?)", roleIDs).Where("permission_id = ?", perm.ID).First(&rolePermission)
+	res = a.DB.Where("role_id IN (?)", roleIDs).Where("permission_id IN (?)", permIDs).First(&rolePermission)
 	if res.Error != nil {
-		return false, nil
+		a.cache.set(userID, key, false)
+		return d, RolePermission{}, nil
 	}
 
-	return true, nil
+	a.cache.set(userID, key, true)
+	d.Allowed = true
+	return d, rolePermission, nil
+}
+
+// CheckPermissionAs checks the permission against the grants of the subject
+// while recording the actor in the audit log, it's meant for impersonation features
+// it accepts the actor id as the first parameter, the subject id as the second parameter
+// and the permission as the third parameter
+// it returns an error if the check could not be recorded
+func (a *Authority) CheckPermissionAs(actorID uuid.UUID, subjectID uuid.UUID, permName string) (bool, error) {
+	ok, err := a.CheckPermission(subjectID, permName)
+
+	res := a.DB.Create(&AuditLog{
+		Action:     AuditCheckPermissionAs,
+		ActorID:    actorID,
+		SubjectID:  subjectID,
+		Permission: permName,
+		Allowed:    ok,
+	})
+	if res.Error != nil {
+		return false, storeError(res.Error)
+	}
+
+	return ok, err
+}
+
+// GetAuditLogs returns the audit entries where the user is either the actor or the subject
+func (a *Authority) GetAuditLogs(userID uuid.UUID) ([]AuditLog, error) {
+	var logs []AuditLog
+	res := a.DB.Where("actor_id = ?", userID).Or("subject_id = ?", userID).Order("id").Find(&logs)
+	return logs, storeError(res.Error)
 }
 
 // CheckRolePermission checks if a role has the permission assigned
@@ -304,9 +611,15 @@ func (a *Authority) CheckRolePermission(roleName string, permName string) (bool,
 
 	}
 
+	// the permission is granted by itself or any permission implying it
+	permIDs, err := a.impliersOf(perm.ID)
+	if err != nil {
+		return false, err
+	}
+
 	// find the rolePermission
 	var rolePermission RolePermission
-	res = a.DB.Where("role_id = ?", role.ID).Where("permission_id = ?", perm.ID).First(&rolePermission)
+	res = a.DB.Where("role_id = ?", role.ID).Where("permission_id IN (?)", permIDs).First(&rolePermission)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
 			return false, nil
@@ -317,11 +630,18 @@ func (a *Authority) CheckRolePermission(roleName string, permName string) (bool,
 	return true, nil
 }
 
+// GetUserPermissions returns the permissions of all the user assigned roles
 func (a *Authority) GetUserPermissions(userID uuid.UUID) ([]string, error) {
+	return a.GetUserPermissionsContext(context.Background(), userID)
+}
+
+// GetUserPermissionsContext returns the permissions of the roles assigned
+// to the user within the tenant of the context
+func (a *Authority) GetUserPermissionsContext(ctx context.Context, userID uuid.UUID) ([]string, error) {
 	var result []string
 
 	var userRoles []UserRole
-	a.DB.Where("user_id = ?", userID).Find(&userRoles)
+	a.userRoles(ctx).Where("user_id = ?", userID).Find(&userRoles)
 
 	var roleIDs []uint
 	for _, r := range userRoles {
@@ -346,84 +666,175 @@ func (a *Authority) GetUserPermissions(userID uuid.UUID) ([]string, error) {
 	return result, nil
 }
 
-// RevokeRole revokes a user's role
-// it returns a error in case of any
-func (a *Authority) RevokeRole(userID uuid.UUID, roleName string) error {
+// RevokeRole revokes a user's role and returns the number of removed assignments
+// it returns ErrNothingToRevoke if the role was not assigned to the user
+func (a *Authority) RevokeRole(userID uuid.UUID, roleName string) (int64, error) {
+	return a.RevokeRoleContext(context.Background(), userID, roleName)
+}
+
+// RevokeRoleContext revokes a user's role within the tenant of the context
+func (a *Authority) RevokeRoleContext(ctx context.Context, userID uuid.UUID, roleName string) (int64, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRevokeRole, UserID: userID, Role: roleName}); err != nil {
+		return 0, err
+	}
+
 	// find the role
 	var role Role
 	res := a.DB.Where("name = ?", roleName).First(&role)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return ErrRoleNotFound
+			return 0, ErrRoleNotFound
 		}
 
 	}
 
-	// revoke the role
-	a.DB.Where("user_id = ?", userID).Where("role_id = ?", role.ID).Delete(UserRole{})
+	// revoke the role along with its event
+	var revoked int64
+	err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		res := tx.Where("tenant_id = ?", TenantFromContext(ctx)).Where("user_id = ?", userID).Where("role_id = ?", role.ID).Delete(UserRole{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return ErrNothingToRevoke
+		}
+		revoked = res.RowsAffected
+		return a.recordEvents(tx, userRoleEvent(EventRoleRevoked, userID, TenantFromContext(ctx), role))
+	})
+	if err != nil {
+		return 0, err
+	}
+	a.invalidate(userID)
 
-	return nil
+	return revoked, nil
 }
 
-// RevokePermission revokes a permission from the user's assigned role
-// it returns an error in case of any
-func (a *Authority) RevokePermission(userID uuid.UUID, permName string) error {
+// RevokePermission revokes a permission from the user's assigned roles
+// and returns the number of removed role permissions
+// it returns ErrNothingToRevoke if none of the user roles had the permission
+func (a *Authority) RevokePermission(userID uuid.UUID, permName string) (int64, error) {
+	return a.RevokePermissionContext(context.Background(), userID, permName)
+}
+
+// RevokePermissionContext revokes a permission from the user's roles assigned within the tenant of the context
+func (a *Authority) RevokePermissionContext(ctx context.Context, userID uuid.UUID, permName string) (int64, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRevokePermission, UserID: userID, Permissions: []string{permName}}); err != nil {
+		return 0, err
+	}
+	db := a.DB.WithContext(ctx)
+
 	// revoke the permission from all roles of the user
 	// find the user roles
 	var userRoles []UserRole
-	res := a.DB.Where("user_id = ?", userID).Find(&userRoles)
+	res := a.userRoles(ctx).Where("user_id = ?", userID).Find(&userRoles)
 	if res.Error != nil {
-		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return nil
-		}
-
+		return 0, storeError(res.Error)
 	}
 
 	// find the permission
 	var perm Permission
-	res = a.DB.Where("name = ?", permName).First(&perm)
+	res = db.Where("name = ?", permName).First(&perm)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return ErrPermissionNotFound
+			return 0, ErrPermissionNotFound
 		}
 
 	}
 
+	var roleIDs []uint
 	for _, r := range userRoles {
-		// revoke the permission
-		a.DB.Where("role_id = ?", r.RoleID).Where("permission_id = ?", perm.ID).Delete(RolePermission{})
+		roleIDs = append(roleIDs, r.RoleID)
+	}
+	roles, err := rolesByID(db, roleIDs)
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	var removed int64
+	for _, r := range userRoles {
+		// revoke the permission along with its event
+		var revoked int64
+		err := a.transaction(db, func(tx *gorm.DB) error {
+			res := tx.Where("role_id = ?", r.RoleID).Where("permission_id = ?", perm.ID).Delete(RolePermission{})
+			if res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res.RowsAffected == 0 {
+				return nil
+			}
+			revoked = res.RowsAffected
+			return a.recordEvents(tx, rolePermissionEvent(EventPermissionRevoked, roles[r.RoleID], perm))
+		})
+		if err != nil {
+			if removed > 0 {
+				a.invalidate(uuid.Nil)
+			}
+			return removed, err
+		}
+		removed += revoked
+	}
+	if removed == 0 {
+		return 0, ErrNothingToRevoke
+	}
+	// the roles might be shared with other users
+	a.invalidate(uuid.Nil)
+
+	return removed, nil
 }
 
 // RevokeRolePermission revokes a permission from a given role
-// it returns an error in case of any
-func (a *Authority) RevokeRolePermission(roleName string, permName string) error {
+// and returns the number of removed role permissions
+// it returns ErrNothingToRevoke if the permission was not assigned to the role
+func (a *Authority) RevokeRolePermission(roleName string, permName string) (int64, error) {
+	return a.RevokeRolePermissionContext(context.Background(), roleName, permName)
+}
+
+// RevokeRolePermissionContext revokes a permission from a role on behalf of the actor of the context
+func (a *Authority) RevokeRolePermissionContext(ctx context.Context, roleName string, permName string) (int64, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRevokeRolePermission, Role: roleName, Permissions: []string{permName}}); err != nil {
+		return 0, err
+	}
+	db := a.DB.WithContext(ctx)
+
 	// find the role
 	var role Role
-	res := a.DB.Where("name = ?", roleName).First(&role)
+	res := db.Where("name = ?", roleName).First(&role)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return ErrRoleNotFound
+			return 0, ErrRoleNotFound
 		}
 
 	}
 
 	// find the permission
 	var perm Permission
-	res = a.DB.Where("name = ?", permName).First(&perm)
+	res = db.Where("name = ?", permName).First(&perm)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return ErrPermissionNotFound
+			return 0, ErrPermissionNotFound
 		}
 
 	}
 
-	// revoke the permission
-	a.DB.Where("role_id = ?", role.ID).Where("permission_id = ?", perm.ID).Delete(RolePermission{})
+	// revoke the permission along with its event
+	var revoked int64
+	err := a.transaction(db, func(tx *gorm.DB) error {
+		res := tx.Where("role_id = ?", role.ID).Where("permission_id = ?", perm.ID).Delete(RolePermission{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return ErrNothingToRevoke
+		}
+		revoked = res.RowsAffected
+		return a.recordEvents(tx, rolePermissionEvent(EventPermissionRevoked, role, perm))
+	})
+	if err != nil {
+		return 0, err
+	}
+	a.invalidate(uuid.Nil)
 
-	return nil
+	return revoked, nil
 }
 
 // GetRoles returns all stored roles
@@ -447,9 +858,14 @@ func (a *Authority) GetRolesData() ([]Role, error) {
 
 // GetUserRoles returns all user assigned roles
 func (a *Authority) GetUserRoles(userID uuid.UUID) ([]string, error) {
+	return a.GetUserRolesContext(context.Background(), userID)
+}
+
+// GetUserRolesContext returns the roles assigned to the user within the tenant of the context
+func (a *Authority) GetUserRolesContext(ctx context.Context, userID uuid.UUID) ([]string, error) {
 	var result []string
 	var userRoles []UserRole
-	a.DB.Where("user_id = ?", userID).Find(&userRoles)
+	a.userRoles(ctx).Where("user_id = ?", userID).Find(&userRoles)
 
 	for _, r := range userRoles {
 		var role Role
@@ -507,66 +923,139 @@ func (a *Authority) GetPermissionsByRole(roleName string) ([]string, error) {
 	return result, nil
 }
 
-// DeleteRole deletes a given role
+// DeleteRole deletes a given role and returns the number of removed rows,
+// the role along with its permission assignments
 // if the role is assigned to a user it returns an error
-func (a *Authority) DeleteRole(roleName string) error {
+func (a *Authority) DeleteRole(roleName string) (int64, error) {
+	return a.DeleteRoleContext(context.Background(), roleName)
+}
+
+// DeleteRoleContext deletes a role on behalf of the actor of the context
+func (a *Authority) DeleteRoleContext(ctx context.Context, roleName string) (int64, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpDeleteRole, Role: roleName}); err != nil {
+		return 0, err
+	}
+	db := a.DB.WithContext(ctx)
+
 	// find the role
 	var role Role
-	res := a.DB.Where("name = ?", roleName).First(&role)
+	res := db.Where("name = ?", roleName).First(&role)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return ErrRoleNotFound
+			return 0, ErrRoleNotFound
 		}
 
 	}
 
 	// check if the role is assigned to a user
 	var userRole UserRole
-	res = a.DB.Where("role_id = ?", role.ID).First(&userRole)
+	res = db.Where("role_id = ?", role.ID).First(&userRole)
 	if res.Error == nil {
 		// role is assigned
-		return ErrRoleInUse
+		return 0, ErrRoleInUse
 	}
 
-	// revoke the assignment of permissions before deleting the role
-	a.DB.Where("role_id = ?", role.ID).Delete(RolePermission{})
+	var removed int64
+	err := a.transaction(db, func(tx *gorm.DB) error {
+		// revoke the assignment of permissions before deleting the role
+		res := tx.Where("role_id = ?", role.ID).Delete(RolePermission{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		removed = res.RowsAffected
 
-	// delete the role
-	a.DB.Where("name = ?", roleName).Delete(Role{})
+		// delete the role
+		res = tx.Where("name = ?", roleName).Delete(Role{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return ErrRoleNotFound
+		}
+		removed += res.RowsAffected
+		return a.recordEvents(tx, AssignmentEvent{Action: EventRoleDeleted, RoleID: role.ID, RoleName: role.Name})
+	})
+	if err != nil {
+		return 0, err
+	}
+	a.invalidate(uuid.Nil)
 
-	return nil
+	return removed, nil
 }
 
-// DeletePermission deletes a given permission
+// DeletePermission deletes a given permission and returns the number of removed rows,
+// the permission along with its implications
 // if the permission is assigned to a role it returns an error
-func (a *Authority) DeletePermission(permName string) error {
+func (a *Authority) DeletePermission(permName string) (int64, error) {
+	return a.DeletePermissionContext(context.Background(), permName)
+}
+
+// DeletePermissionContext deletes a permission on behalf of the actor of the context
+func (a *Authority) DeletePermissionContext(ctx context.Context, permName string) (int64, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpDeletePermission, Permissions: []string{permName}}); err != nil {
+		return 0, err
+	}
+	db := a.DB.WithContext(ctx)
+
 	// find the permission
 	var perm Permission
-	res := a.DB.Where("name = ?", permName).First(&perm)
+	res := db.Where("name = ?", permName).First(&perm)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-			return ErrPermissionNotFound
+			return 0, ErrPermissionNotFound
 		}
 
 	}
 
 	// check if the permission is assigned to a role
 	var rolePermission RolePermission
-	res = a.DB.Where("permission_id = ?", perm.ID).First(&rolePermission)
+	res = db.Where("permission_id = ?", perm.ID).First(&rolePermission)
 	if res.Error == nil {
 		// role is assigned
-		return ErrPermissionInUse
+		return 0, ErrPermissionInUse
 	}
 
-	// delete the permission
-	a.DB.Where("name = ?", permName).Delete(Permission{})
+	var removed int64
+	err := a.transaction(db, func(tx *gorm.DB) error {
+		// drop the implications of the permission before deleting it
+		res := tx.Where("permission_id = ?", perm.ID).Or("implied_permission_id = ?", perm.ID).Delete(PermissionImplication{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		removed = res.RowsAffected
 
-	return nil
+		// delete the permission
+		res = tx.Where("name = ?", permName).Delete(Permission{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return ErrPermissionNotFound
+		}
+		removed += res.RowsAffected
+		return a.recordEvents(tx, AssignmentEvent{Action: EventPermissionDeleted, PermissionID: perm.ID, PermissionName: perm.Name})
+	})
+	if err != nil {
+		return 0, err
+	}
+	a.invalidate(uuid.Nil)
+
+	return removed, nil
 }
 
 func (a *Authority) UpdateRole(roleID uint, NewRoleName string, NewDesc string) error {
+	return a.UpdateRoleContext(context.Background(), roleID, NewRoleName, NewDesc)
+}
+
+// UpdateRoleContext updates a role by id on behalf of the actor of the context
+func (a *Authority) UpdateRoleContext(ctx context.Context, roleID uint, NewRoleName string, NewDesc string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpUpdateRole, Role: NewRoleName}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+
 	var role Role
-	res := a.DB.Where("id = ?", roleID).Find(&role)
+	res := db.Where("id = ?", roleID).Find(&role)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
 			return nil
@@ -574,13 +1063,24 @@ func (a *Authority) UpdateRole(roleID uint, NewRoleName string, NewDesc string)
 	}
 	role.Name = NewRoleName
 	role.Description = NewDesc
-	a.DB.Model(&role).Updates(&role)
+	db.Model(&role).Updates(&role)
+	a.invalidate(uuid.Nil)
 	return nil
 }
 
 func (a *Authority) UpdatePermission(permissionID uint, NewPermissionName string, NewDesc string) error {
+	return a.UpdatePermissionContext(context.Background(), permissionID, NewPermissionName, NewDesc)
+}
+
+// UpdatePermissionContext updates a permission by id on behalf of the actor of the context
+func (a *Authority) UpdatePermissionContext(ctx context.Context, permissionID uint, NewPermissionName string, NewDesc string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpUpdatePermission, Permissions: []string{NewPermissionName}}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+
 	var permission Permission
-	res := a.DB.Where("id = ?", permissionID).Find(&permission)
+	res := db.Where("id = ?", permissionID).Find(&permission)
 	if res.Error != nil {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
 			return nil
@@ -588,13 +1088,171 @@ func (a *Authority) UpdatePermission(permissionID uint, NewPermissionName string
 	}
 	permission.Name = NewPermissionName
 	permission.Description = NewDesc
-	a.DB.Model(&permission).Updates(&permission)
+	db.Model(&permission).Updates(&permission)
+	a.invalidate(uuid.Nil)
+	return nil
+}
+
+// UpdateRoleByName renames a role and updates its description
+// it returns an error if the role is not present in the database
+// it returns an error if the new name is taken by another role
+func (a *Authority) UpdateRoleByName(roleName string, newRoleName string, newDesc string) error {
+	return a.UpdateRoleByNameContext(context.Background(), roleName, newRoleName, newDesc)
+}
+
+// UpdateRoleByNameContext renames a role on behalf of the actor of the context
+func (a *Authority) UpdateRoleByNameContext(ctx context.Context, roleName string, newRoleName string, newDesc string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpUpdateRole, Role: roleName}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+
+	var role Role
+	res := db.Where("name = ?", roleName).First(&role)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return ErrRoleNotFound
+		}
+		return storeError(res.Error)
+	}
+
+	// make sure the new name is not taken
+	if newRoleName != roleName {
+		var c int64
+		res = db.Model(Role{}).Where("name = ?", newRoleName).Count(&c)
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if c > 0 {
+			return ErrRoleNameConflict
+		}
+	}
+
+	res = db.Model(&role).Updates(map[string]interface{}{"name": newRoleName, "description": newDesc})
+	if res.Error != nil {
+		return storeError(res.Error)
+	}
+	a.invalidate(uuid.Nil)
+
 	return nil
 }
 
-func migrateTables(db *gorm.DB) {
-	db.AutoMigrate(&Role{})
-	db.AutoMigrate(&Permission{})
-	db.AutoMigrate(&RolePermission{})
-	db.AutoMigrate(&UserRole{})
+// UpdatePermissionByName renames a permission and updates its description
+// it returns an error if the permission is not present in the database
+// it returns an error if the new name is taken by another permission
+func (a *Authority) UpdatePermissionByName(permName string, newPermName string, newDesc string) error {
+	return a.UpdatePermissionByNameContext(context.Background(), permName, newPermName, newDesc)
+}
+
+// UpdatePermissionByNameContext renames a permission on behalf of the actor of the context
+func (a *Authority) UpdatePermissionByNameContext(ctx context.Context, permName string, newPermName string, newDesc string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpUpdatePermission, Permissions: []string{permName}}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+
+	var perm Permission
+	res := db.Where("name = ?", permName).First(&perm)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return ErrPermissionNotFound
+		}
+		return storeError(res.Error)
+	}
+
+	// make sure the new name is not taken
+	if newPermName != permName {
+		var c int64
+		res = db.Model(Permission{}).Where("name = ?", newPermName).Count(&c)
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if c > 0 {
+			return ErrPermissionNameConflict
+		}
+	}
+
+	res = db.Model(&perm).Updates(map[string]interface{}{"name": newPermName, "description": newDesc})
+	if res.Error != nil {
+		return storeError(res.Error)
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// findRole returns the role matching the name
+func (a *Authority) findRole(roleName string) (Role, error) {
+	var role Role
+	res := a.DB.Where("name = ?", roleName).First(&role)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return role, ErrRoleNotFound
+		}
+		return role, storeError(res.Error)
+	}
+
+	return role, nil
+}
+
+// findPermission returns the permission matching the name
+func (a *Authority) findPermission(permName string) (Permission, error) {
+	var perm Permission
+	res := a.DB.Where("name = ?", permName).First(&perm)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return perm, ErrPermissionNotFound
+		}
+		return perm, storeError(res.Error)
+	}
+
+	return perm, nil
+}
+
+// migrateTables migrates the tables of the models with the prefix of the instance
+// the duplicated names are merged first if asked to
+// every model is migrated, it returns an error wrapping the first migration failure
+// or ErrDuplicateNames if the unique indexes of the names are missing
+func (a *Authority) migrateTables(mergeNames bool) error {
+	if mergeNames {
+		if _, err := a.mergeDuplicateNames(); err != nil {
+			return err
+		}
+	}
+
+	var first error
+	for _, m := range models() {
+		if err := a.DB.Table(a.tableName(m)).AutoMigrate(m); err != nil && first == nil {
+			first = storeError(fmt.Errorf("migrating %T: %w", m, err))
+		}
+	}
+
+	// the creates rely on the unique names, they are refused without the indexes
+	for _, m := range []model{&Role{}, &Permission{}} {
+		if !a.DB.Table(a.tableName(m)).Migrator().HasIndex(m, "Name") {
+			a.duplicateNames = true
+			if first == nil {
+				first = fmt.Errorf("table %s", a.tableName(m))
+			}
+			return wrapError(ErrDuplicateNames, first)
+		}
+	}
+
+	return first
+}
+
+// models returns the models of the tables
+func models() []model {
+	return []model{
+		&Role{},
+		&Permission{},
+		&RolePermission{},
+		&UserRole{},
+		&AuditLog{},
+		&PermissionImplication{},
+		&PermissionNamespace{},
+		&JobState{},
+		&AssignmentEvent{},
+		&PermissionUsage{},
+	}
 }
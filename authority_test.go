@@ -1,9 +1,11 @@
 package authority_test
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/faozimipa/authority"
@@ -69,8 +71,25 @@ func TestCreateRole(t *testing.T) {
 		t.Error("unexpected duplicated entries for role")
 	}
 
+	// test concurrent creates
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := auth.CreateRole("role-b", "b description role"); err != nil {
+				t.Error("an error was not expected while creating role concurrently ", err)
+			}
+		}()
+	}
+	wg.Wait()
+	db.Model(authority.Role{}).Where("name = ?", "role-b").Count(&c)
+	if c != 1 {
+		t.Error("expecting a single role to be stored by concurrent creates")
+	}
+
 	// clean up
-	db.Where("name = ?", "role-a").Delete(authority.Role{})
+	db.Where("name IN (?)", []string{"role-a", "role-b"}).Delete(authority.Role{})
 }
 
 func TestCreatePermission(t *testing.T) {
@@ -103,8 +122,25 @@ func TestCreatePermission(t *testing.T) {
 		t.Error("unexpected duplicated entries for permission")
 	}
 
+	// test concurrent creates
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := auth.CreatePermission("permission-b", "b description permission"); err != nil {
+				t.Error("an error was not expected while creating permission concurrently ", err)
+			}
+		}()
+	}
+	wg.Wait()
+	db.Model(authority.Permission{}).Where("name = ?", "permission-b").Count(&c)
+	if c != 1 {
+		t.Error("expecting a single permission to be stored by concurrent creates")
+	}
+
 	// clean up
-	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+	db.Where("name IN (?)", []string{"permission-a", "permission-b"}).Delete(authority.Permission{})
 }
 
 func TestAssignPermission(t *testing.T) {
@@ -432,12 +468,20 @@ func TestRevokeRole(t *testing.T) {
 	}
 
 	//test
-	err = auth.RevokeRole(id, "role-a")
+	removed, err := auth.RevokeRole(id, "role-a")
 	if err != nil {
 		t.Error("unexpected error revoking user role.", err)
 	}
+	if removed != 1 {
+		t.Error("expecting one removed assignment")
+	}
+	// revoke the role again
+	_, err = auth.RevokeRole(id, "role-a")
+	if !errors.Is(err, authority.ErrNothingToRevoke) {
+		t.Error("expecting an error when revoking a role not assigned")
+	}
 	// revoke missing role
-	err = auth.RevokeRole(id, "role-aa")
+	_, err = auth.RevokeRole(id, "role-aa")
 	if err == nil {
 		t.Error("expecting error when revoking a missing role")
 	}
@@ -491,19 +535,22 @@ func TestRevokePermission(t *testing.T) {
 	}
 
 	// case: user not assigned role
-	err = auth.RevokePermission(id2, "permission-a")
-	if err != nil {
-		t.Error("expecting error to be nil", err)
+	_, err = auth.RevokePermission(id2, "permission-a")
+	if !errors.Is(err, authority.ErrNothingToRevoke) {
+		t.Error("expecting an error when the user has no role", err)
 	}
 
 	// test
-	err = auth.RevokePermission(id, "permission-a")
+	removed, err := auth.RevokePermission(id, "permission-a")
 	if err != nil {
 		t.Error("unexpected error while revoking role permissions.", err)
 	}
+	if removed != 1 {
+		t.Error("expecting one removed role permission")
+	}
 
 	// revoke missing permissin
-	err = auth.RevokePermission(id, "permission-aa")
+	_, err = auth.RevokePermission(id, "permission-aa")
 	if err == nil {
 		t.Error("expecting error when revoking a missing permission")
 	}
@@ -553,21 +600,28 @@ func TestRevokeRolePermission(t *testing.T) {
 	}
 
 	// test revoke missing role
-	err = auth.RevokeRolePermission("role-aa", "permission-a")
+	_, err = auth.RevokeRolePermission("role-aa", "permission-a")
 	if err == nil {
 		t.Error("expecting an error when revoking a missing role")
 	}
 
 	// test revoke missing permission
-	err = auth.RevokeRolePermission("role-a", "permission-aa")
+	_, err = auth.RevokeRolePermission("role-a", "permission-aa")
 	if err == nil {
 		t.Error("expecting an error when revoking a missing permission")
 	}
 
-	err = auth.RevokeRolePermission("role-a", "permission-a")
+	removed, err := auth.RevokeRolePermission("role-a", "permission-a")
 	if err != nil {
 		t.Error("unexpected error while revoking role permissions.", err)
 	}
+	if removed != 1 {
+		t.Error("expecting one removed role permission")
+	}
+	_, err = auth.RevokeRolePermission("role-a", "permission-a")
+	if !errors.Is(err, authority.ErrNothingToRevoke) {
+		t.Error("expecting an error when revoking a permission not assigned")
+	}
 	// assert, count assigned permission, should be one
 	var r authority.Role
 	db.Where("name = ?", "role-a").First(&r)
@@ -648,7 +702,7 @@ func TestDeleteRole(t *testing.T) {
 	}
 
 	// test delete a missing role
-	err = auth.DeleteRole("role-aa")
+	_, err = auth.DeleteRole("role-aa")
 	if err == nil {
 		t.Error("expecting an error when deleting a missing role")
 	}
@@ -657,16 +711,19 @@ func TestDeleteRole(t *testing.T) {
 	id := uuid.New()
 
 	auth.AssignRole(id, "role-a")
-	err = auth.DeleteRole("role-a")
+	_, err = auth.DeleteRole("role-a")
 	if err == nil {
 		t.Error("expecting an error when deleting an assigned role")
 	}
 	auth.RevokeRole(id, "role-a")
 
-	err = auth.DeleteRole("role-a")
+	removed, err := auth.DeleteRole("role-a")
 	if err != nil {
 		t.Error("unexpected error while deleting role.", err)
 	}
+	if removed != 1 {
+		t.Error("expecting the role to be removed")
+	}
 
 	var c int64
 	db.Model(authority.Role{}).Count(&c)
@@ -687,7 +744,7 @@ func TestDeletePermission(t *testing.T) {
 	}
 
 	// delete missing permission
-	err = auth.DeletePermission("permission-aa")
+	_, err = auth.DeletePermission("permission-aa")
 	if err == nil {
 		t.Error("expecting an error when deleting a missing permission")
 	}
@@ -697,17 +754,20 @@ func TestDeletePermission(t *testing.T) {
 	auth.AssignPermissions("role-a", []string{"permission-a"})
 
 	// delete assinged permission
-	err = auth.DeletePermission("permission-a")
+	_, err = auth.DeletePermission("permission-a")
 	if err == nil {
 		t.Error("expecting an error when deleting assigned permission")
 	}
 
 	auth.RevokeRolePermission("role-a", "permission-a")
 
-	err = auth.DeletePermission("permission-a")
+	removed, err := auth.DeletePermission("permission-a")
 	if err != nil {
 		t.Error("unexpected error while deleting permission.", err)
 	}
+	if removed != 1 {
+		t.Error("expecting the permission to be removed")
+	}
 
 	var c int64
 	db.Model(authority.Permission{}).Count(&c)
@@ -751,6 +811,128 @@ func TestGetUserRoles(t *testing.T) {
 	db.Where("name = ?", "role-b").Delete(authority.Role{})
 }
 
+func TestCheckPermissionAs(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	actor := uuid.New()
+	subject := uuid.New()
+	auth.AssignRole(subject, "role-a")
+
+	// the check is evaluated against the subject grants
+	ok, err := auth.CheckPermissionAs(actor, subject, "permission-a")
+	if err != nil {
+		t.Error("unexpected error while checking permission as another user.", err)
+	}
+	if !ok {
+		t.Error("expecting true to be returned")
+	}
+	ok, _ = auth.CheckPermissionAs(subject, actor, "permission-a")
+	if ok {
+		t.Error("expecting false when the subject doesn't have the permission")
+	}
+
+	// the actor is recorded in the audit log
+	logs, err := auth.GetAuditLogs(actor)
+	if err != nil {
+		t.Error("unexpected error while getting audit logs.", err)
+	}
+	if len(logs) != 2 {
+		t.Error("expecting two audit entries to be returned")
+	}
+	if len(logs) > 0 && (logs[0].ActorID != actor || logs[0].SubjectID != subject || !logs[0].Allowed) {
+		t.Error("unexpected audit entry")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("actor_id = ?", actor).Or("actor_id = ?", subject).Delete(authority.AuditLog{})
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestUpdateRoleByName(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreateRole("role-b", "a description role")
+
+	// rename a role
+	err := auth.UpdateRoleByName("role-a", "role-c", "a new description")
+	if err != nil {
+		t.Error("unexpected error while updating role.", err)
+	}
+	var r authority.Role
+	db.Where("name = ?", "role-c").First(&r)
+	if r.Description != "a new description" {
+		t.Error("failed updating role")
+	}
+
+	// update a missing role
+	err = auth.UpdateRoleByName("role-aa", "role-d", "a description role")
+	if !errors.Is(err, authority.ErrRoleNotFound) {
+		t.Error("expecting an error when updating a missing role")
+	}
+
+	// rename to an existing name
+	err = auth.UpdateRoleByName("role-c", "role-b", "a description role")
+	if !errors.Is(err, authority.ErrRoleNameConflict) {
+		t.Error("expecting an error when renaming to an existing role")
+	}
+
+	// clean up
+	db.Where("name = ?", "role-b").Delete(authority.Role{})
+	db.Where("name = ?", "role-c").Delete(authority.Role{})
+}
+
+func TestUpdatePermissionByName(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.CreatePermission("permission-b", "a description permission")
+
+	// rename a permission
+	err := auth.UpdatePermissionByName("permission-a", "permission-c", "a new description")
+	if err != nil {
+		t.Error("unexpected error while updating permission.", err)
+	}
+	var p authority.Permission
+	db.Where("name = ?", "permission-c").First(&p)
+	if p.Description != "a new description" {
+		t.Error("failed updating permission")
+	}
+
+	// update a missing permission
+	err = auth.UpdatePermissionByName("permission-aa", "permission-d", "a description permission")
+	if !errors.Is(err, authority.ErrPermissionNotFound) {
+		t.Error("expecting an error when updating a missing permission")
+	}
+
+	// rename to an existing name
+	err = auth.UpdatePermissionByName("permission-c", "permission-b", "a description permission")
+	if !errors.Is(err, authority.ErrPermissionNameConflict) {
+		t.Error("expecting an error when renaming to an existing permission")
+	}
+
+	// clean up
+	db.Where("name = ?", "permission-b").Delete(authority.Permission{})
+	db.Where("name = ?", "permission-c").Delete(authority.Permission{})
+}
+
 func sliceHasString(s []string, val string) bool {
 	for _, v := range s {
 		if v == val {
@@ -760,3 +942,26 @@ func sliceHasString(s []string, val string) bool {
 
 	return false
 }
+
+func TestResolve(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+	if authority.Resolve() != auth {
+		t.Error("expecting the last initiated instance")
+	}
+
+	billing := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+	authority.Register("billing", billing)
+	authority.Register("default", auth)
+	if authority.Resolve("billing") != billing || authority.Resolve("default") != auth {
+		t.Error("expecting the registered instances")
+	}
+	if authority.Resolve("missing") != nil {
+		t.Error("expecting nil for a missing name")
+	}
+}
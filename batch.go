@@ -0,0 +1,295 @@
+package authority
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BatchWriterOptions has the options of a batch writer
+type BatchWriterOptions struct {
+	// BatchSize is the number of mutations applied per transaction, defaults to 500
+	BatchSize int
+	// Concurrency is the number of transactions applied at the same time, defaults to 1
+	Concurrency int
+}
+
+// BatchError describes a rejected mutation of a batch writer
+type BatchError struct {
+	UserID   uuid.UUID
+	RoleName string
+	Err      error
+}
+
+// BatchReport summarizes the mutations applied by a batch writer
+type BatchReport struct {
+	// Assigned is the number of roles assigned
+	Assigned int
+	// Revoked is the number of roles revoked
+	Revoked int
+	// Skipped is the number of mutations without effect when they were applied
+	// like assigning an assigned role
+	Skipped int
+	// Errors are the rejected mutations
+	Errors []BatchError
+}
+
+// batchOp is a mutation waiting to be applied
+type batchOp struct {
+	assign   bool
+	userID   uuid.UUID
+	roleName string
+}
+
+// BatchWriter groups the role assignments and revokes into sized transactions
+// applied by a fixed number of workers, the mutations of a user are always
+// applied by the same worker in the order they were written
+// the writes block while the workers are busy so a fast producer can't outrun the database
+// it's not safe for concurrent writes
+type BatchWriter struct {
+	auth *Authority
+	ctx  context.Context
+	size int
+
+	pending [][]batchOp
+	queues  []chan []batchOp
+	wg      sync.WaitGroup
+	closed  bool
+
+	mu     sync.Mutex
+	report BatchReport
+	err    error
+}
+
+// NewBatchWriter returns a batch writer applying the mutations within the tenant of the context
+// the context is checked before every write, Close must be called to apply the remaining mutations
+func (a *Authority) NewBatchWriter(ctx context.Context, opts BatchWriterOptions) *BatchWriter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	w := &BatchWriter{
+		auth:    a,
+		ctx:     ctx,
+		size:    opts.BatchSize,
+		pending: make([][]batchOp, opts.Concurrency),
+		queues:  make([]chan []batchOp, opts.Concurrency),
+	}
+	for i := range w.queues {
+		w.queues[i] = make(chan []batchOp)
+		w.wg.Add(1)
+		go w.work(w.queues[i])
+	}
+
+	return w
+}
+
+// AssignRole queues the assignment of the role to the user
+// it returns the error that stopped the writer if any
+func (w *BatchWriter) AssignRole(userID uuid.UUID, roleName string) error {
+	return w.write(batchOp{assign: true, userID: userID, roleName: roleName})
+}
+
+// RevokeRole queues the revoke of the role from the user
+// it returns the error that stopped the writer if any
+func (w *BatchWriter) RevokeRole(userID uuid.UUID, roleName string) error {
+	return w.write(batchOp{userID: userID, roleName: roleName})
+}
+
+// Close applies the remaining mutations and waits for the workers
+// it returns the report of the applied mutations with the error that stopped the writer if any
+func (w *BatchWriter) Close() (*BatchReport, error) {
+	if !w.closed {
+		w.closed = true
+		if err := w.ctx.Err(); err != nil {
+			w.mu.Lock()
+			if w.err == nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+		for i, ops := range w.pending {
+			if len(ops) > 0 && w.failure() == nil {
+				w.queues[i] <- ops
+			}
+			close(w.queues[i])
+		}
+		w.wg.Wait()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	report := w.report
+	return &report, w.err
+}
+
+// write checks the mutation and adds it to the pending batch of the user worker
+// a full batch is handed to the worker, waiting for it if it's busy
+func (w *BatchWriter) write(op batchOp) error {
+	if err := w.failure(); err != nil {
+		return err
+	}
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	operation := OpRevokeRole
+	if op.assign {
+		operation = OpAssignRole
+	}
+	if err := w.auth.checkMutation(w.ctx, Mutation{Operation: operation, UserID: op.userID, Role: op.roleName}); err != nil {
+		return err
+	}
+
+	i := int(op.userID[len(op.userID)-1]) % len(w.queues)
+	w.pending[i] = append(w.pending[i], op)
+	if len(w.pending[i]) < w.size {
+		return nil
+	}
+
+	ops := w.pending[i]
+	w.pending[i] = nil
+	select {
+	case w.queues[i] <- ops:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
+
+// work applies the batches of the queue until it's closed, the batches
+// received after a failure are dropped
+func (w *BatchWriter) work(queue chan []batchOp) {
+	defer w.wg.Done()
+	for ops := range queue {
+		if w.failure() != nil {
+			continue
+		}
+		var report BatchReport
+		err := w.auth.applyBatch(w.ctx, ops, &report)
+
+		w.mu.Lock()
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+		if err == nil {
+			w.report.Assigned += report.Assigned
+			w.report.Revoked += report.Revoked
+			w.report.Skipped += report.Skipped
+			w.report.Errors = append(w.report.Errors, report.Errors...)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// failure returns the error that stopped the writer if any
+func (w *BatchWriter) failure() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// applyBatch applies the mutations in a transaction, only the net change of every
+// assignment is written so an assign followed by a revoke writes nothing
+func (a *Authority) applyBatch(ctx context.Context, ops []batchOp, report *BatchReport) error {
+	tenant := TenantFromContext(ctx)
+	type assignment struct {
+		userID uuid.UUID
+		roleID uint
+	}
+
+	changes := 0
+	err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		// find the roles at once
+		var roleNames []string
+		var userIDs []uuid.UUID
+		for _, op := range ops {
+			roleNames = append(roleNames, op.roleName)
+			userIDs = append(userIDs, op.userID)
+		}
+		var roles []Role
+		if res := tx.Where("name IN (?)", roleNames).Find(&roles); res.Error != nil {
+			return storeError(res.Error)
+		}
+		rolesByName := map[string]Role{}
+		for _, r := range roles {
+			rolesByName[r.Name] = r
+		}
+
+		// find the existing assignments at once
+		var existing []UserRole
+		if res := tx.Where("tenant_id = ?", tenant).Where("user_id IN (?)", userIDs).Find(&existing); res.Error != nil {
+			return storeError(res.Error)
+		}
+		initial := map[assignment]bool{}
+		for _, ur := range existing {
+			initial[assignment{ur.UserID, ur.RoleID}] = true
+		}
+
+		// replay the mutations in order to get the final state
+		final := map[assignment]bool{}
+		var touched []assignment
+		for _, op := range ops {
+			role, found := rolesByName[op.roleName]
+			if !found {
+				report.Errors = append(report.Errors, BatchError{UserID: op.userID, RoleName: op.roleName, Err: ErrRoleNotFound})
+				continue
+			}
+			key := assignment{op.userID, role.ID}
+			state, seen := final[key]
+			if !seen {
+				state = initial[key]
+				touched = append(touched, key)
+			}
+			if state == op.assign {
+				report.Skipped++
+			}
+			final[key] = op.assign
+		}
+
+		var creates []UserRole
+		var events []AssignmentEvent
+		roleNamesByID := map[uint]string{}
+		for _, r := range roles {
+			roleNamesByID[r.ID] = r.Name
+		}
+		for _, key := range touched {
+			if final[key] == initial[key] {
+				continue
+			}
+			role := Role{ID: key.roleID, Name: roleNamesByID[key.roleID]}
+			changes++
+			if final[key] {
+				creates = append(creates, UserRole{UserID: key.userID, RoleID: key.roleID, TenantID: tenant})
+				events = append(events, userRoleEvent(EventRoleAssigned, key.userID, tenant, role))
+				continue
+			}
+			res := tx.Where("tenant_id = ?", tenant).Where("user_id = ?", key.userID).Where("role_id = ?", key.roleID).Delete(UserRole{})
+			if res.Error != nil {
+				return storeError(res.Error)
+			}
+			report.Revoked++
+			events = append(events, userRoleEvent(EventRoleRevoked, key.userID, tenant, role))
+		}
+
+		if len(creates) > 0 {
+			if res := tx.Create(&creates); res.Error != nil {
+				return storeError(res.Error)
+			}
+			report.Assigned += len(creates)
+		}
+		return a.recordEvents(tx, events...)
+	})
+	if err != nil {
+		return err
+	}
+	if changes > 0 {
+		a.invalidate(uuid.Nil)
+	}
+
+	return nil
+}
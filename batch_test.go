@@ -0,0 +1,105 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestBatchWriter(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreateRole("role-b", "a description role")
+	existing := uuid.New()
+	auth.AssignRole(existing, "role-a")
+
+	w := auth.NewBatchWriter(context.Background(), authority.BatchWriterOptions{BatchSize: 3})
+	var users []uuid.UUID
+	for i := 0; i < 10; i++ {
+		id := uuid.New()
+		users = append(users, id)
+		if err := w.AssignRole(id, "role-a"); err != nil {
+			t.Error("unexpected error while writing.", err)
+		}
+	}
+	w.AssignRole(existing, "role-a")
+	w.AssignRole(users[1], "role-x")
+	report, err := w.Close()
+	if err != nil {
+		t.Error("unexpected error while closing the writer.", err)
+	}
+	if report.Assigned != 10 || report.Revoked != 0 || report.Skipped != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(report.Errors) != 1 || !errors.Is(report.Errors[0].Err, authority.ErrRoleNotFound) {
+		t.Error("expecting the missing role to be reported")
+	}
+
+	// only the net change of a batch is written
+	w = auth.NewBatchWriter(context.Background(), authority.BatchWriterOptions{})
+	w.AssignRole(existing, "role-b")
+	w.RevokeRole(existing, "role-b")
+	w.RevokeRole(users[0], "role-a")
+	report, _ = w.Close()
+	if report.Assigned != 0 || report.Revoked != 1 || report.Skipped != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	ok, _ := auth.CheckRole(users[0], "role-a")
+	if ok {
+		t.Error("expecting the role assigned then revoked not to be assigned")
+	}
+	ok, _ = auth.CheckRole(users[9], "role-a")
+	if !ok {
+		t.Error("expecting the role to be assigned")
+	}
+	ok, _ = auth.CheckRole(existing, "role-b")
+	if ok {
+		t.Error("expecting the role assigned then revoked not to be assigned")
+	}
+
+	// revokes are applied in batches too
+	w = auth.NewBatchWriter(context.Background(), authority.BatchWriterOptions{BatchSize: 2})
+	for _, id := range append(users, existing) {
+		w.RevokeRole(id, "role-a")
+	}
+	report, _ = w.Close()
+	if report.Revoked != 10 || report.Skipped != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// the batches are spread over the workers
+	w = auth.NewBatchWriter(context.Background(), authority.BatchWriterOptions{BatchSize: 2, Concurrency: 4})
+	for _, id := range users {
+		w.AssignRole(id, "role-b")
+	}
+	report, err = w.Close()
+	if err != nil || report.Assigned != 10 {
+		t.Errorf("unexpected report %+v %v", report, err)
+	}
+
+	// a canceled context stops the writer
+	ctx, cancel := context.WithCancel(context.Background())
+	w = auth.NewBatchWriter(ctx, authority.BatchWriterOptions{})
+	cancel()
+	if err := w.AssignRole(existing, "role-a"); !errors.Is(err, context.Canceled) {
+		t.Error("expecting the context error to be returned")
+	}
+	if _, err := w.Close(); !errors.Is(err, context.Canceled) {
+		t.Error("expecting the context error to be returned by close")
+	}
+
+	// clean up
+	auth.DeleteRole("role-a")
+	auth.ForceDeleteRole(context.Background(), "role-b")
+	for _, id := range append(users, existing) {
+		db.Where("user_id = ?", id).Delete(authority.AssignmentEvent{})
+	}
+}
@@ -0,0 +1,176 @@
+package authority
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// bulkBatchSize is the number of rows removed per statement by the forced deletes
+const bulkBatchSize = 500
+
+// DeleteReport summarizes a forced delete, it reports the progress made
+// when the delete is stopped by the context
+type DeleteReport struct {
+	// Revoked is the number of assignments removed
+	Revoked int64
+	// Deleted reports whether the role or permission itself was deleted
+	Deleted bool
+}
+
+// ImportAssignmentsCSVContext imports the assignments like ImportAssignmentsCSV
+// within the tenant of the context
+// the context is checked before every batch, if it's canceled the import stops
+// and the report of the applied batches is returned with the context error
+func (a *Authority) ImportAssignmentsCSVContext(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	return a.importCSV(ctx, r, opts, 0, nil)
+}
+
+// ForceDeleteRole revokes the role from all users in batches then deletes it
+// the context is checked between the batches, if it's canceled the delete stops
+// and the report of the revoked assignments is returned with the context error
+// it returns ErrRoleNotFound if the role is not present in the database
+func (a *Authority) ForceDeleteRole(ctx context.Context, roleName string) (*DeleteReport, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpDeleteRole, Role: roleName}); err != nil {
+		return &DeleteReport{}, err
+	}
+
+	report := &DeleteReport{}
+	role, err := a.findRole(roleName)
+	if err != nil {
+		return report, err
+	}
+
+	revoke := func(tx *gorm.DB) (int64, error) {
+		var userRoles []UserRole
+		if res := tx.Where("role_id = ?", role.ID).Limit(bulkBatchSize).Find(&userRoles); res.Error != nil {
+			return 0, storeError(res.Error)
+		}
+		if len(userRoles) == 0 {
+			return 0, nil
+		}
+		var events []AssignmentEvent
+		for _, ur := range userRoles {
+			events = append(events, userRoleEvent(EventRoleRevoked, ur.UserID, ur.TenantID, role))
+		}
+		if err := a.recordEvents(tx, events...); err != nil {
+			return 0, err
+		}
+		res := userRolesOf(tx, userRoles).Delete(UserRole{})
+		return res.RowsAffected, storeError(res.Error)
+	}
+	if err := a.deleteInBatches(ctx, revoke, report); err != nil {
+		return report, err
+	}
+
+	err = a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		if res := tx.Where("role_id = ?", role.ID).Delete(RolePermission{}); res.Error != nil {
+			return storeError(res.Error)
+		}
+		if res := tx.Where("id = ?", role.ID).Delete(Role{}); res.Error != nil {
+			return storeError(res.Error)
+		}
+		return a.recordEvents(tx, AssignmentEvent{Action: EventRoleDeleted, RoleID: role.ID, RoleName: role.Name})
+	})
+	if err != nil {
+		return report, err
+	}
+	report.Deleted = true
+	a.invalidate(uuid.Nil)
+
+	return report, nil
+}
+
+// ForceDeletePermission revokes the permission from all roles in batches then deletes it
+// the context is checked between the batches, if it's canceled the delete stops
+// and the report of the revoked assignments is returned with the context error
+// it returns ErrPermissionNotFound if the permission is not present in the database
+func (a *Authority) ForceDeletePermission(ctx context.Context, permName string) (*DeleteReport, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpDeletePermission, Permissions: []string{permName}}); err != nil {
+		return &DeleteReport{}, err
+	}
+
+	report := &DeleteReport{}
+	perm, err := a.findPermission(permName)
+	if err != nil {
+		return report, err
+	}
+
+	revoke := func(tx *gorm.DB) (int64, error) {
+		var rolePerms []RolePermission
+		if res := tx.Where("permission_id = ?", perm.ID).Limit(bulkBatchSize).Find(&rolePerms); res.Error != nil {
+			return 0, storeError(res.Error)
+		}
+		if len(rolePerms) == 0 {
+			return 0, nil
+		}
+		var ids, roleIDs []uint
+		for _, rp := range rolePerms {
+			ids = append(ids, rp.ID)
+			roleIDs = append(roleIDs, rp.RoleID)
+		}
+		roles, err := rolesByID(tx, roleIDs)
+		if err != nil {
+			return 0, err
+		}
+		var events []AssignmentEvent
+		for _, rp := range rolePerms {
+			events = append(events, rolePermissionEvent(EventPermissionRevoked, roles[rp.RoleID], perm))
+		}
+		if err := a.recordEvents(tx, events...); err != nil {
+			return 0, err
+		}
+		res := tx.Where("id IN (?)", ids).Delete(RolePermission{})
+		return res.RowsAffected, storeError(res.Error)
+	}
+	if err := a.deleteInBatches(ctx, revoke, report); err != nil {
+		return report, err
+	}
+
+	err = a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		res := tx.Where("permission_id = ?", perm.ID).Or("implied_permission_id = ?", perm.ID).Delete(PermissionImplication{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if res := tx.Where("id = ?", perm.ID).Delete(Permission{}); res.Error != nil {
+			return storeError(res.Error)
+		}
+		return a.recordEvents(tx, AssignmentEvent{Action: EventPermissionDeleted, PermissionID: perm.ID, PermissionName: perm.Name})
+	})
+	if err != nil {
+		return report, err
+	}
+	report.Deleted = true
+	a.invalidate(uuid.Nil)
+
+	return report, nil
+}
+
+// deleteInBatches calls the revoke func in a transaction for every batch until nothing
+// is left, the func deletes a batch of rows along with their events and returns the
+// number of rows deleted
+// the context is checked between the batches
+func (a *Authority) deleteInBatches(ctx context.Context, revoke func(tx *gorm.DB) (int64, error), report *DeleteReport) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var deleted int64
+		err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+			var err error
+			deleted, err = revoke(tx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return nil
+		}
+		report.Revoked += deleted
+		a.invalidate(uuid.Nil)
+	}
+}
@@ -0,0 +1,200 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestImportAssignmentsCSVContext(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	var ids []uuid.UUID
+	var csv strings.Builder
+	for i := 0; i < 4; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		fmt.Fprintf(&csv, "%s,role-a\n", id)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := auth.ImportAssignmentsCSVContext(ctx, strings.NewReader(csv.String()), authority.ImportOptions{BatchSize: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Error("expecting the import to be canceled")
+	}
+	if report.Assigned != 0 {
+		t.Errorf("expecting nothing to be assigned with a canceled context, got %+v", report)
+	}
+	var count int64
+	db.Model(&authority.UserRole{}).Where("user_id IN (?)", ids).Count(&count)
+	if count != 0 {
+		t.Error("expecting nothing to be written with a canceled context")
+	}
+
+	// the import stops before the next batch once canceled
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	report, err = auth.ImportAssignmentsCSVContext(ctx, &cancelingReader{r: strings.NewReader(csv.String()), after: 3, cancel: cancel}, authority.ImportOptions{BatchSize: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Error("expecting the import to be canceled")
+	}
+	if report.Assigned != 2 {
+		t.Errorf("expecting the report of the first batch, got %+v", report)
+	}
+
+	// clean up
+	db.Where("user_id IN (?)", ids).Delete(authority.UserRole{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestImportAssignmentsCSVTenant(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	userA, userB := uuid.New(), uuid.New()
+	tenantA := authority.WithTenant(context.Background(), "tenant-a")
+	auth.AssignRoleContext(tenantA, userA, "role-a")
+
+	// the rows are assigned within the tenant of the context
+	tenants := map[string]bool{}
+	auth.SetMutationHook(func(ctx context.Context, m authority.Mutation) error {
+		tenants[m.Tenant] = true
+		return nil
+	})
+	defer auth.SetMutationHook(nil)
+	csv := fmt.Sprintf("%s,role-a\n%s,role-a\n", userA, userB)
+	report, err := auth.ImportAssignmentsCSVContext(tenantA, strings.NewReader(csv), authority.ImportOptions{})
+	if err != nil {
+		t.Error("unexpected error while importing assignments.", err)
+	}
+	if report.Assigned != 1 || report.Skipped != 1 {
+		t.Errorf("expecting the assignment of the tenant to be skipped, got %+v", report)
+	}
+	if len(tenants) != 1 || !tenants["tenant-a"] {
+		t.Errorf("expecting the hook to get the tenant of the context, got %v", tenants)
+	}
+	ok, _ := auth.CheckRoleContext(tenantA, userB, "role-a")
+	if !ok {
+		t.Error("expecting the imported role to be assigned within the tenant")
+	}
+	ok, _ = auth.CheckRole(userB, "role-a")
+	if ok {
+		t.Error("expecting the imported role not to be assigned outside of the tenant")
+	}
+
+	// clean up
+	db.Where("user_id IN (?)", []uuid.UUID{userA, userB}).Delete(authority.AssignmentEvent{})
+	db.Where("user_id IN (?)", []uuid.UUID{userA, userB}).Delete(authority.UserRole{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+// cancelingReader cancels the context once the given number of lines are read
+type cancelingReader struct {
+	r      *strings.Reader
+	after  int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p[0] = b
+	if b == '\n' {
+		c.after--
+		if c.after == 0 {
+			c.cancel()
+		}
+	}
+	return 1, nil
+}
+
+func TestForceDeleteRole(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		auth.AssignRole(id, "role-a")
+	}
+
+	// a canceled context stops before the first batch
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := auth.ForceDeleteRole(ctx, "role-a")
+	if !errors.Is(err, context.Canceled) {
+		t.Error("expecting the delete to be canceled")
+	}
+	if report.Revoked != 0 || report.Deleted {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	report, err = auth.ForceDeleteRole(context.Background(), "role-a")
+	if err != nil {
+		t.Error("unexpected error while force deleting role.", err)
+	}
+	if report.Revoked != 3 || !report.Deleted {
+		t.Errorf("unexpected report %+v", report)
+	}
+	_, err = auth.CheckRole(ids[0], "role-a")
+	if !errors.Is(err, authority.ErrRoleNotFound) {
+		t.Error("expecting the role to be deleted")
+	}
+
+	_, err = auth.ForceDeleteRole(context.Background(), "role-a")
+	if !errors.Is(err, authority.ErrRoleNotFound) {
+		t.Error("expecting an error when deleting a missing role")
+	}
+
+	// clean up
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+}
+
+func TestForceDeletePermission(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreateRole("role-b", "b description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	auth.AssignPermissions("role-b", []string{"permission-a"})
+
+	report, err := auth.ForceDeletePermission(context.Background(), "permission-a")
+	if err != nil {
+		t.Error("unexpected error while force deleting permission.", err)
+	}
+	if report.Revoked != 2 || !report.Deleted {
+		t.Errorf("unexpected report %+v", report)
+	}
+	perms, _ := auth.GetPermissions()
+	if sliceHasString(perms, "permission-a") {
+		t.Error("expecting the permission to be deleted")
+	}
+
+	// clean up
+	db.Where("name IN (?)", []string{"role-a", "role-b"}).Delete(authority.Role{})
+}
@@ -0,0 +1,188 @@
+package authority
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvalidationEvent describes a cache invalidation shared between instances
+// a role id invalidates the cached checks of the users assigned the role,
+// otherwise a nil user id invalidates the cached checks of every user
+type InvalidationEvent struct {
+	Origin string    `json:"origin"`
+	UserID uuid.UUID `json:"user_id"`
+	RoleID uint      `json:"role_id,omitempty"`
+}
+
+// CacheNotifier broadcasts invalidation events to the other instances
+// sharing the same database
+type CacheNotifier interface {
+	Notify(ev InvalidationEvent) error
+}
+
+// checkCache holds the results of the user checks for a limited time
+type checkCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	ok        bool
+	expiresAt time.Time
+}
+
+func newCheckCache(ttl time.Duration, clock Clock) *checkCache {
+	return &checkCache{ttl: ttl, clock: clock, entries: map[uuid.UUID]map[string]cacheEntry{}}
+}
+
+func (c *checkCache) get(userID uuid.UUID, key string) (bool, bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, found := c.entries[userID][key]
+	if !found || c.clock.Now().After(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.ok, true
+}
+
+func (c *checkCache) set(userID uuid.UUID, key string, ok bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[userID] == nil {
+		c.entries[userID] = map[string]cacheEntry{}
+	}
+	c.entries[userID][key] = cacheEntry{ok: ok, expiresAt: c.clock.Now().Add(c.ttl)}
+}
+
+func (c *checkCache) invalidate(userID uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if userID == uuid.Nil {
+		c.entries = map[uuid.UUID]map[string]cacheEntry{}
+		return
+	}
+	delete(c.entries, userID)
+}
+
+func (c *checkCache) invalidateUsers(userIDs []uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range userIDs {
+		delete(c.entries, id)
+	}
+}
+
+// SetCacheNotifier sets the notifier used to broadcast invalidations
+// made by this instance to the other instances
+func (a *Authority) SetCacheNotifier(n CacheNotifier) {
+	a.notifierMu.Lock()
+	defer a.notifierMu.Unlock()
+	a.notifier = n
+}
+
+// HandleInvalidation applies an invalidation event received from another instance
+// events originated from this instance are ignored
+func (a *Authority) HandleInvalidation(ev InvalidationEvent) {
+	if ev.Origin == a.instanceID {
+		return
+	}
+	if ev.RoleID != 0 {
+		if err := a.invalidateRoleUsers(ev.RoleID); err != nil {
+			// the users of the role are unknown, every user is dropped instead
+			a.logf("authority: %v", err)
+			a.cache.invalidate(uuid.Nil)
+		}
+		return
+	}
+	a.cache.invalidate(ev.UserID)
+}
+
+// invalidate drops the cached checks of the user locally and on the other instances
+// a nil user id drops the cached checks of every user
+func (a *Authority) invalidate(userID uuid.UUID) {
+	a.cache.invalidate(userID)
+	a.notify(InvalidationEvent{Origin: a.instanceID, UserID: userID})
+}
+
+// notify broadcasts the invalidation event to the other instances if a notifier is set
+func (a *Authority) notify(ev InvalidationEvent) {
+	a.notifierMu.RLock()
+	notifier := a.notifier
+	a.notifierMu.RUnlock()
+	if notifier != nil {
+		if err := notifier.Notify(ev); err != nil {
+			a.logf("authority: cache invalidation not broadcast: %v", err)
+		}
+	}
+}
+
+// invalidateRoleUsers drops the cached checks of the users assigned the role locally
+func (a *Authority) invalidateRoleUsers(roleID uint) error {
+	if a.cache == nil {
+		return nil
+	}
+
+	var userIDs []uuid.UUID
+	if res := a.DB.Model(&UserRole{}).Where("role_id = ?", roleID).Distinct().Pluck("user_id", &userIDs); res.Error != nil {
+		return storeError(res.Error)
+	}
+	a.cache.invalidateUsers(userIDs)
+
+	return nil
+}
+
+// InvalidateUser drops the cached checks of the user locally and on the other instances
+// it's meant for the changes made out of band, like manual sql fixes
+func (a *Authority) InvalidateUser(userID uuid.UUID) {
+	if userID == uuid.Nil {
+		return
+	}
+	a.invalidate(userID)
+}
+
+// InvalidateRole drops the cached checks of the users assigned the role in any tenant
+// locally and on the other instances, a single event is broadcast for all the users
+// it returns ErrRoleNotFound if the role is not present in the database
+func (a *Authority) InvalidateRole(roleName string) error {
+	role, err := a.findRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	if err := a.invalidateRoleUsers(role.ID); err != nil {
+		return err
+	}
+	a.notify(InvalidationEvent{Origin: a.instanceID, RoleID: role.ID})
+
+	return nil
+}
+
+// InvalidateAll drops the cached checks of every user locally and on the other instances
+// and the results cached by the federation clients of this instance
+func (a *Authority) InvalidateAll() {
+	a.invalidate(uuid.Nil)
+
+	a.fedMu.RLock()
+	defer a.fedMu.RUnlock()
+	for _, c := range a.federation {
+		c.Flush()
+	}
+}
@@ -0,0 +1,177 @@
+package authority_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+// linkedNotifier delivers invalidation events to other in-process instances
+type linkedNotifier struct {
+	peers  []*authority.Authority
+	events []authority.InvalidationEvent
+}
+
+func (n *linkedNotifier) Notify(ev authority.InvalidationEvent) error {
+	n.events = append(n.events, ev)
+	for _, p := range n.peers {
+		p.HandleInvalidation(ev)
+	}
+	return nil
+}
+
+func TestCheckCache(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		CacheTTL:     time.Minute,
+	})
+	peer := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		CacheTTL:     time.Minute,
+	})
+	auth.SetCacheNotifier(&linkedNotifier{peers: []*authority.Authority{peer}})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	id := uuid.New()
+	auth.AssignRole(id, "role-a")
+
+	ok, _ := peer.CheckPermission(id, "permission-a")
+	if !ok {
+		t.Error("expecting true to be returned")
+	}
+
+	// out of band changes are not seen while cached
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("user_id = ?", id).Delete(authority.UserRole{})
+	ok, _ = peer.CheckPermission(id, "permission-a")
+	if !ok {
+		t.Error("expecting the cached result to be returned")
+	}
+
+	// changes made by another instance invalidate the cache
+	auth.AssignRole(id, "role-a")
+	auth.RevokeRole(id, "role-a")
+	ok, _ = peer.CheckPermission(id, "permission-a")
+	if ok {
+		t.Error("expecting false after the role is revoked by another instance")
+	}
+
+	// clean up
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestInvalidate(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		CacheTTL:     time.Minute,
+	})
+
+	peer := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		CacheTTL:     time.Minute,
+	})
+	notifier := &linkedNotifier{peers: []*authority.Authority{peer}}
+	auth.SetCacheNotifier(notifier)
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	first, second := uuid.New(), uuid.New()
+	auth.AssignRole(first, "role-a")
+	auth.AssignRole(second, "role-a")
+	auth.CheckPermission(first, "permission-a")
+	auth.CheckPermission(second, "permission-a")
+
+	// out of band changes are seen once the user is invalidated
+	db.Where("user_id IN (?)", []uuid.UUID{first, second}).Delete(authority.UserRole{})
+	auth.InvalidateUser(first)
+	ok, _ := auth.CheckPermission(first, "permission-a")
+	if ok {
+		t.Error("expecting false after the user is invalidated")
+	}
+	ok, _ = auth.CheckPermission(second, "permission-a")
+	if !ok {
+		t.Error("expecting the cached result of the other user to be kept")
+	}
+
+	// the users of the role are invalidated
+	auth.AssignRole(first, "role-a")
+	auth.AssignRole(second, "role-a")
+	auth.CheckPermission(first, "permission-a")
+	peer.CheckPermission(second, "permission-a")
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	notifier.events = nil
+	if err := auth.InvalidateRole("role-a"); err != nil {
+		t.Error("unexpected error while invalidating the role.", err)
+	}
+	ok, _ = auth.CheckPermission(first, "permission-a")
+	if ok {
+		t.Error("expecting false after the role is invalidated")
+	}
+	if len(notifier.events) != 1 || notifier.events[0].RoleID != r.ID {
+		t.Errorf("expecting a single event for the role, got %+v", notifier.events)
+	}
+	ok, _ = peer.CheckPermission(second, "permission-a")
+	if ok {
+		t.Error("expecting false after the role is invalidated by another instance")
+	}
+	if err := auth.InvalidateRole("role-x"); err != authority.ErrRoleNotFound {
+		t.Error("expecting an error when invalidating a missing role")
+	}
+
+	// all users are invalidated
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	auth.CheckPermission(second, "permission-a")
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	auth.InvalidateAll()
+	ok, _ = auth.CheckPermission(second, "permission-a")
+	if ok {
+		t.Error("expecting false after everything is invalidated")
+	}
+
+	// clean up
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("user_id IN (?)", []uuid.UUID{first, second}).Delete(authority.AssignmentEvent{})
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestSetCacheNotifier(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		CacheTTL:     time.Minute,
+	})
+
+	// the notifier can be set while the invalidations are broadcast
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			auth.SetCacheNotifier(&linkedNotifier{})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			auth.InvalidateUser(uuid.New())
+		}
+	}()
+	wg.Wait()
+}
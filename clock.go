@@ -0,0 +1,41 @@
+package authority
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Clock is the time source of the timestamps, the cache expiry and the decisions
+// it lets the tests simulate time passing without sleeping
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default clock reading the system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets the time source, defaults to the system time
+func WithClock(c Clock) Option {
+	return func(o *Options) {
+		o.Clock = c
+	}
+}
+
+// withClock returns a session of the database stamping the records with the clock time
+func withClock(db *gorm.DB, c Clock) *gorm.DB {
+	return db.Session(&gorm.Session{NowFunc: func() time.Time { return c.Now().Local() }})
+}
+
+// now returns the current time of the clock
+func (a *Authority) now() time.Time {
+	if a.clock == nil {
+		return time.Now()
+	}
+
+	return a.clock.Now()
+}
@@ -0,0 +1,84 @@
+package authority_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+// fakeClock is a clock moved forward by the tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)}
+	auth, err := authority.NewWithOptions(
+		authority.WithDB(db),
+		authority.WithPrefix("authority_"),
+		authority.WithCache(time.Minute),
+		authority.WithClock(clock),
+	)
+	if err != nil {
+		t.Fatal("unexpected error while initiating authority.", err)
+	}
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+	assigned := clock.Now()
+
+	// the cached checks expire with the clock
+	ok, _ := auth.CheckPermission(userID, "permission-a")
+	if !ok {
+		t.Error("expecting true to be returned")
+	}
+	db.Where("user_id = ?", userID).Delete(authority.UserRole{})
+	ok, _ = auth.CheckPermission(userID, "permission-a")
+	if !ok {
+		t.Error("expecting the cached result to be returned")
+	}
+	clock.Advance(2 * time.Minute)
+	ok, _ = auth.CheckPermission(userID, "permission-a")
+	if ok {
+		t.Error("expecting the cached result to expire")
+	}
+
+	// the decisions and the history are stamped with the clock
+	d, _ := auth.CheckPermissionDecision(context.Background(), userID, "permission-a")
+	if !d.EvaluatedAt.Equal(clock.Now()) {
+		t.Error("expecting the decision to be stamped with the clock time")
+	}
+	roles, _ := auth.GetUserRolesAt(userID, assigned.Add(time.Second))
+	if len(roles) != 1 || roles[0] != "role-a" {
+		t.Error("expecting the assignment to be stamped with the clock time")
+	}
+	roles, _ = auth.GetUserRolesAt(userID, assigned.Add(-time.Second))
+	if len(roles) != 0 {
+		t.Error("expecting no roles before the clock time of the assignment")
+	}
+
+	// clean up
+	auth.DeleteRole("role-a")
+	auth.DeletePermission("permission-a")
+	db.Where("user_id = ?", userID).Delete(authority.AssignmentEvent{})
+}
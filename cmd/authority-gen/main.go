@@ -0,0 +1,60 @@
+// authority-gen emits a go file of typed permission constants from a policy export
+// or from the database, it's meant to be run with go generate
+//
+//	//go:generate go run github.com/faozimipa/authority/cmd/authority-gen -policy policy.json -package perm -o perm/permissions.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/faozimipa/authority"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func main() {
+	policy := flag.String("policy", "", "the policy export json file")
+	dsn := flag.String("dsn", "", "the mysql dsn to read the policy from instead of a file")
+	prefix := flag.String("prefix", "authority_", "the tables prefix when reading from the database")
+	pkg := flag.String("package", "perm", "the package of the generated file")
+	out := flag.String("o", "permissions.go", "the generated file")
+	flag.Parse()
+
+	var export *authority.PolicyExport
+	switch {
+	case *policy != "":
+		data, err := os.ReadFile(*policy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		export = &authority.PolicyExport{}
+		if err := json.Unmarshal(data, export); err != nil {
+			log.Fatal(err)
+		}
+	case *dsn != "":
+		db, err := gorm.Open(mysql.Open(*dsn), &gorm.Config{})
+		if err != nil {
+			log.Fatal(err)
+		}
+		// read only so the generator never migrates the database it reads
+		auth := authority.New(authority.Options{TablesPrefix: *prefix, DB: db, ReadOnly: true})
+		export, err = auth.ExportPolicy()
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatal("expecting either -policy or -dsn")
+	}
+
+	var b bytes.Buffer
+	if err := authority.GeneratePermissionConstants(&b, *pkg, export); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, b.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
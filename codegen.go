@@ -0,0 +1,103 @@
+package authority
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// GeneratePermissionConstants writes a go file of typed constants for the permissions
+// of the export, "posts.edit" becomes PostsEdit, along with helpers checking them
+// it returns an error if two permissions map to the same identifier
+func GeneratePermissionConstants(w io.Writer, pkg string, export *PolicyExport) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by authority-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "import (\n\t\"context\"\n\n\t\"github.com/faozimipa/authority\"\n\t\"github.com/google/uuid\"\n)\n\n")
+	fmt.Fprintf(&b, "// Permission is a permission name known at compile time\ntype Permission string\n\n")
+
+	seen := map[string]string{}
+	var idents []string
+	fmt.Fprintf(&b, "// the permissions of the policy\nconst (\n")
+	for _, p := range export.Permissions {
+		ident := permissionIdent(p.Name)
+		if other, found := seen[ident]; found {
+			return fmt.Errorf("permissions %q and %q both map to %s", other, p.Name, ident)
+		}
+		seen[ident] = p.Name
+		idents = append(idents, ident)
+		if p.Description != "" {
+			fmt.Fprintf(&b, "\t// %s %s\n", ident, strings.Join(strings.Fields(p.Description), " "))
+		}
+		fmt.Fprintf(&b, "\t%s Permission = %q\n", ident, p.Name)
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	fmt.Fprintf(&b, "// All returns all the permissions\nfunc All() []Permission {\n\treturn []Permission{\n")
+	for _, ident := range idents {
+		fmt.Fprintf(&b, "\t\t%s,\n", ident)
+	}
+	fmt.Fprintf(&b, "\t}\n}\n\n")
+
+	fmt.Fprintf(&b, `// Names returns the names of all the permissions, suitable for ValidatePolicyReferences
+func Names() []string {
+	var names []string
+	for _, p := range All() {
+		names = append(names, string(p))
+	}
+	return names
+}
+
+// String returns the permission name
+func (p Permission) String() string {
+	return string(p)
+}
+
+// Check checks if the user has the permission
+func (p Permission) Check(a *authority.Authority, userID uuid.UUID) (bool, error) {
+	return a.CheckPermission(userID, string(p))
+}
+
+// CheckContext checks if the user has the permission within the tenant of the context
+func (p Permission) CheckContext(ctx context.Context, a *authority.Authority, userID uuid.UUID) (bool, error) {
+	return a.CheckPermissionContext(ctx, userID, string(p))
+}
+`)
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// reservedIdents are the identifiers declared by the generated file
+var reservedIdents = map[string]bool{"All": true, "Names": true, "Permission": true}
+
+// permissionIdent returns the exported go identifier of a permission name
+// the identifiers declared by the generated file get a Perm suffix
+func permissionIdent(permName string) string {
+	words := strings.FieldsFunc(permName, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, w := range words {
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	ident := b.String()
+	if ident == "" || !unicode.IsLetter([]rune(ident)[0]) {
+		ident = "P" + ident
+	}
+	if reservedIdents[ident] {
+		ident += "Perm"
+	}
+
+	return ident
+}
@@ -0,0 +1,72 @@
+package authority_test
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+)
+
+func TestGeneratePermissionConstants(t *testing.T) {
+	export := &authority.PolicyExport{
+		Permissions: []authority.ExportedPermission{
+			{Name: "posts.edit", Description: "edit the posts"},
+			{Name: "2fa.reset"},
+		},
+	}
+
+	var b strings.Builder
+	err := authority.GeneratePermissionConstants(&b, "perm", export)
+	if err != nil {
+		t.Error("unexpected error while generating constants.", err)
+	}
+	src := b.String()
+	if !strings.Contains(src, "package perm") {
+		t.Error("expecting the package clause")
+	}
+	if !strings.Contains(src, `PostsEdit Permission = "posts.edit"`) || !strings.Contains(src, `P2faReset Permission = "2fa.reset"`) {
+		t.Errorf("expecting the typed constants, got\n%s", src)
+	}
+
+	export.Permissions = append(export.Permissions, authority.ExportedPermission{Name: "posts-edit"})
+	err = authority.GeneratePermissionConstants(&b, "perm", export)
+	if err == nil {
+		t.Error("expecting an error when two permissions map to the same identifier")
+	}
+}
+
+func TestGeneratePermissionConstantsReserved(t *testing.T) {
+	export := &authority.PolicyExport{
+		Permissions: []authority.ExportedPermission{
+			{Name: "all"},
+			{Name: "names"},
+			{Name: "permission"},
+		},
+	}
+
+	var b strings.Builder
+	err := authority.GeneratePermissionConstants(&b, "perm", export)
+	if err != nil {
+		t.Error("unexpected error while generating constants.", err)
+	}
+	src := b.String()
+	flat := strings.Join(strings.Fields(src), " ")
+	for _, decl := range []string{`AllPerm Permission = "all"`, `NamesPerm Permission = "names"`, `PermissionPerm Permission = "permission"`} {
+		if !strings.Contains(flat, decl) {
+			t.Errorf("expecting %s for the reserved identifier, got\n%s", decl, src)
+		}
+	}
+	_, err = parser.ParseFile(token.NewFileSet(), "permissions.go", src, parser.DeclarationErrors)
+	if err != nil {
+		t.Error("expecting the generated file to declare every identifier once.", err)
+	}
+
+	// the suffixed identifier may collide with another permission
+	export.Permissions = append(export.Permissions, authority.ExportedPermission{Name: "all.perm"})
+	err = authority.GeneratePermissionConstants(&b, "perm", export)
+	if err == nil {
+		t.Error("expecting an error when two permissions map to the same identifier")
+	}
+}
@@ -0,0 +1,64 @@
+package authority
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type contextKey int
+
+const (
+	tenantKey contextKey = iota
+	loaderKey
+	actorKey
+	eventTimeKey
+)
+
+// WithTenant returns a copy of the context carrying the tenant id
+// the context aware methods scope the user roles to this tenant
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// TenantFromContext returns the tenant id carried by the context
+// it returns an empty string, the global scope, if there is none
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey).(string)
+	return tenantID
+}
+
+// WithActor returns a copy of the context carrying the id of the user making the changes
+// it's passed to the mutation hook to enforce quotas per admin
+func WithActor(ctx context.Context, actorID uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey, actorID)
+}
+
+// ActorFromContext returns the actor id carried by the context, uuid.Nil if there is none
+func ActorFromContext(ctx context.Context) uuid.UUID {
+	actorID, _ := ctx.Value(actorKey).(uuid.UUID)
+	return actorID
+}
+
+// withEventTime returns a copy of the context setting the time of the events recorded with it
+func withEventTime(ctx context.Context, at time.Time) context.Context {
+	return context.WithValue(ctx, eventTimeKey, at)
+}
+
+// eventTimeFromContext returns the time of the events recorded with the context if any
+func eventTimeFromContext(ctx context.Context) (time.Time, bool) {
+	at, ok := ctx.Value(eventTimeKey).(time.Time)
+	return at, ok && !at.IsZero()
+}
+
+// userRoles returns a query on the user roles of the context tenant
+func (a *Authority) userRoles(ctx context.Context) *gorm.DB {
+	return a.DB.WithContext(ctx).Where("tenant_id = ?", TenantFromContext(ctx))
+}
+
+// cacheKey returns the key of a cached check within the context tenant
+func cacheKey(ctx context.Context, kind string, name string) string {
+	return TenantFromContext(ctx) + "|" + kind + ":" + name
+}
@@ -0,0 +1,74 @@
+package authority_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestTenantContext(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+
+	id := uuid.New()
+	tenantA := authority.WithTenant(context.Background(), "tenant-a")
+	tenantB := authority.WithTenant(context.Background(), "tenant-b")
+	if authority.TenantFromContext(tenantA) != "tenant-a" {
+		t.Error("expecting the tenant to be carried by the context")
+	}
+
+	err := auth.AssignRoleContext(tenantA, id, "role-a")
+	if err != nil {
+		t.Error("unexpected error while assigning role within a tenant.", err)
+	}
+
+	// the assignment is scoped to the tenant
+	ok, _ := auth.CheckRoleContext(tenantA, id, "role-a")
+	if !ok {
+		t.Error("expecting the role to be assigned within the tenant")
+	}
+	ok, _ = auth.CheckPermissionContext(tenantA, id, "permission-a")
+	if !ok {
+		t.Error("expecting the permission to be granted within the tenant")
+	}
+	ok, _ = auth.CheckPermissionContext(tenantB, id, "permission-a")
+	if ok {
+		t.Error("expecting false within another tenant")
+	}
+	ok, _ = auth.CheckRole(id, "role-a")
+	if ok {
+		t.Error("expecting false within the global scope")
+	}
+	roles, _ := auth.GetUserRolesContext(tenantA, id)
+	if len(roles) != 1 || roles[0] != "role-a" {
+		t.Error("expecting the tenant roles to be returned")
+	}
+
+	// revoking within another tenant keeps the assignment
+	auth.RevokeRoleContext(tenantB, id, "role-a")
+	ok, _ = auth.CheckRoleContext(tenantA, id, "role-a")
+	if !ok {
+		t.Error("expecting the role to be kept")
+	}
+	auth.RevokeRoleContext(tenantA, id, "role-a")
+	ok, _ = auth.CheckRoleContext(tenantA, id, "role-a")
+	if ok {
+		t.Error("expecting the role to be revoked")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
@@ -0,0 +1,79 @@
+package authority
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the sources of a decision
+const (
+	SourceLocal     = "local"
+	SourceFederated = "federated"
+)
+
+// Decision is the result of a permission check along with its metadata
+type Decision struct {
+	Allowed    bool   `json:"allowed"`
+	Permission string `json:"permission"`
+	// Description is the description of the checked permission, it's meant for
+	// the messages shown to the users, empty for the checks delegated to the owning service
+	Description string `json:"description,omitempty"`
+	// Source is SourceLocal or SourceFederated for the checks delegated to the owning service
+	Source string `json:"source"`
+	// Role is the role granting the permission, empty if denied or answered from the cache
+	Role            string `json:"role,omitempty"`
+	RoleDescription string `json:"role_description,omitempty"`
+	// GrantedBy is the assigned permission granting the checked one, it differs from
+	// the checked permission when granted through an implication
+	GrantedBy            string    `json:"granted_by,omitempty"`
+	GrantedByDescription string    `json:"granted_by_description,omitempty"`
+	EvaluatedAt          time.Time `json:"evaluated_at"`
+	CacheHit             bool      `json:"cache_hit"`
+}
+
+// CheckPermissionDecision checks if the user has the permission like CheckPermissionContext
+// and returns the decision metadata along with the result
+// it returns an error if the permission is not present in the database
+func (a *Authority) CheckPermissionDecision(ctx context.Context, userID uuid.UUID, permName string) (Decision, error) {
+	if c := a.federationClient(permName); c != nil {
+		d := Decision{Permission: permName, Source: SourceFederated, EvaluatedAt: a.now()}
+		ok, err := c.CheckPermissionContext(ctx, userID, permName)
+		d.Allowed = ok
+		a.countCheck(permName, ok, err)
+		return d, err
+	}
+
+	d, rp, err := a.evaluateLocal(ctx, userID, permName)
+	a.countCheck(permName, d.Allowed, err)
+	if err != nil {
+		return d, err
+	}
+	if d.CacheHit {
+		// the cached checks don't load the permission
+		var perm Permission
+		res := a.DB.WithContext(ctx).Where("name = ?", permName).Limit(1).Find(&perm)
+		if res.Error != nil {
+			return d, storeError(res.Error)
+		}
+		d.Description = perm.Description
+	}
+	if rp.ID == 0 {
+		return d, nil
+	}
+
+	// name the granting role and permission
+	var role Role
+	if res := a.DB.WithContext(ctx).Where("id = ?", rp.RoleID).First(&role); res.Error != nil {
+		return d, storeError(res.Error)
+	}
+	var perm Permission
+	if res := a.DB.WithContext(ctx).Where("id = ?", rp.PermissionID).First(&perm); res.Error != nil {
+		return d, storeError(res.Error)
+	}
+	d.Role, d.RoleDescription = role.Name, role.Description
+	d.GrantedBy, d.GrantedByDescription = perm.Name, perm.Description
+
+	return d, nil
+}
@@ -0,0 +1,66 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestCheckPermissionDecision(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		CacheTTL:     time.Minute,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.CreatePermission("permission-b", "b description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	auth.AddPermissionImplication("permission-a", "permission-b")
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	d, err := auth.CheckPermissionDecision(context.Background(), userID, "permission-b")
+	if err != nil {
+		t.Error("unexpected error while checking permission decision.", err)
+	}
+	if !d.Allowed || d.Source != authority.SourceLocal || d.CacheHit {
+		t.Errorf("unexpected decision %+v", d)
+	}
+	if d.Role != "role-a" || d.GrantedBy != "permission-a" {
+		t.Error("expecting the granting role and implying permission")
+	}
+	if d.Description != "b description permission" || d.RoleDescription != "a description role" || d.GrantedByDescription != "a description permission" {
+		t.Errorf("unexpected descriptions %+v", d)
+	}
+	if d.EvaluatedAt.IsZero() {
+		t.Error("expecting the evaluation time")
+	}
+
+	d, _ = auth.CheckPermissionDecision(context.Background(), userID, "permission-b")
+	if !d.Allowed || !d.CacheHit {
+		t.Error("expecting the second decision to be answered from the cache")
+	}
+	if d.Description != "b description permission" {
+		t.Error("expecting the description of a cached decision")
+	}
+
+	_, err = auth.CheckPermissionDecision(context.Background(), userID, "permission-x")
+	if !errors.Is(err, authority.ErrPermissionNotFound) {
+		t.Error("expecting an error for a missing permission")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	auth.RemovePermissionImplication("permission-a", "permission-b")
+	db.Where("name IN (?)", []string{"permission-a", "permission-b"}).Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
@@ -0,0 +1,186 @@
+package authority
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PolicyDiff is the difference between two policy exports, from the first to the second
+type PolicyDiff struct {
+	AddedRoles          []string         `json:"added_roles,omitempty"`
+	RemovedRoles        []string         `json:"removed_roles,omitempty"`
+	ChangedRoles        []RoleDiff       `json:"changed_roles,omitempty"`
+	AddedPermissions    []string         `json:"added_permissions,omitempty"`
+	RemovedPermissions  []string         `json:"removed_permissions,omitempty"`
+	ChangedPermissions  []PermissionDiff `json:"changed_permissions,omitempty"`
+	AddedImplications   []string         `json:"added_implications,omitempty"`
+	RemovedImplications []string         `json:"removed_implications,omitempty"`
+}
+
+// RoleDiff is the difference of a role present in both exports
+type RoleDiff struct {
+	Name               string   `json:"name"`
+	DescriptionFrom    string   `json:"description_from,omitempty"`
+	DescriptionTo      string   `json:"description_to,omitempty"`
+	AddedPermissions   []string `json:"added_permissions,omitempty"`
+	RemovedPermissions []string `json:"removed_permissions,omitempty"`
+}
+
+// PermissionDiff is the difference of a permission present in both exports
+type PermissionDiff struct {
+	Name            string `json:"name"`
+	DescriptionFrom string `json:"description_from"`
+	DescriptionTo   string `json:"description_to"`
+}
+
+// DiffPolicies returns the changes needed to turn the first export into the second
+// the implications are reported as "permission -> implied permission"
+func DiffPolicies(from, to *PolicyExport) *PolicyDiff {
+	d := &PolicyDiff{}
+
+	fromRoles := map[string]ExportedRole{}
+	for _, r := range from.Roles {
+		fromRoles[r.Name] = r
+	}
+	toRoles := map[string]ExportedRole{}
+	for _, r := range to.Roles {
+		toRoles[r.Name] = r
+	}
+	for _, r := range to.Roles {
+		old, found := fromRoles[r.Name]
+		if !found {
+			d.AddedRoles = append(d.AddedRoles, r.Name)
+			continue
+		}
+		rd := RoleDiff{Name: r.Name}
+		if old.Description != r.Description {
+			rd.DescriptionFrom, rd.DescriptionTo = old.Description, r.Description
+		}
+		rd.AddedPermissions, rd.RemovedPermissions = diffNames(old.Permissions, r.Permissions)
+		if rd.DescriptionFrom != rd.DescriptionTo || len(rd.AddedPermissions) > 0 || len(rd.RemovedPermissions) > 0 {
+			d.ChangedRoles = append(d.ChangedRoles, rd)
+		}
+	}
+	for _, r := range from.Roles {
+		if _, found := toRoles[r.Name]; !found {
+			d.RemovedRoles = append(d.RemovedRoles, r.Name)
+		}
+	}
+
+	fromPerms := map[string]ExportedPermission{}
+	for _, p := range from.Permissions {
+		fromPerms[p.Name] = p
+	}
+	toPerms := map[string]ExportedPermission{}
+	for _, p := range to.Permissions {
+		toPerms[p.Name] = p
+	}
+	for _, p := range to.Permissions {
+		old, found := fromPerms[p.Name]
+		if !found {
+			d.AddedPermissions = append(d.AddedPermissions, p.Name)
+		} else if old.Description != p.Description {
+			d.ChangedPermissions = append(d.ChangedPermissions, PermissionDiff{Name: p.Name, DescriptionFrom: old.Description, DescriptionTo: p.Description})
+		}
+	}
+	for _, p := range from.Permissions {
+		if _, found := toPerms[p.Name]; !found {
+			d.RemovedPermissions = append(d.RemovedPermissions, p.Name)
+		}
+	}
+
+	d.AddedImplications, d.RemovedImplications = diffNames(implicationEdges(from.Implications), implicationEdges(to.Implications))
+
+	sort.Strings(d.AddedRoles)
+	sort.Strings(d.RemovedRoles)
+	sort.Slice(d.ChangedRoles, func(i, j int) bool { return d.ChangedRoles[i].Name < d.ChangedRoles[j].Name })
+	sort.Strings(d.AddedPermissions)
+	sort.Strings(d.RemovedPermissions)
+	sort.Slice(d.ChangedPermissions, func(i, j int) bool { return d.ChangedPermissions[i].Name < d.ChangedPermissions[j].Name })
+
+	return d
+}
+
+// Empty reports whether the exports are the same
+func (d *PolicyDiff) Empty() bool {
+	return len(d.AddedRoles) == 0 && len(d.RemovedRoles) == 0 && len(d.ChangedRoles) == 0 &&
+		len(d.AddedPermissions) == 0 && len(d.RemovedPermissions) == 0 && len(d.ChangedPermissions) == 0 &&
+		len(d.AddedImplications) == 0 && len(d.RemovedImplications) == 0
+}
+
+// String returns a human readable diff, one change per line
+// prefixed with + for the additions, - for the removals and ~ for the changes
+func (d *PolicyDiff) String() string {
+	var b strings.Builder
+	for _, name := range d.AddedRoles {
+		fmt.Fprintf(&b, "+ role %s\n", name)
+	}
+	for _, name := range d.RemovedRoles {
+		fmt.Fprintf(&b, "- role %s\n", name)
+	}
+	for _, r := range d.ChangedRoles {
+		if r.DescriptionFrom != r.DescriptionTo {
+			fmt.Fprintf(&b, "~ role %s description %q -> %q\n", r.Name, r.DescriptionFrom, r.DescriptionTo)
+		}
+		for _, name := range r.AddedPermissions {
+			fmt.Fprintf(&b, "+ role %s permission %s\n", r.Name, name)
+		}
+		for _, name := range r.RemovedPermissions {
+			fmt.Fprintf(&b, "- role %s permission %s\n", r.Name, name)
+		}
+	}
+	for _, name := range d.AddedPermissions {
+		fmt.Fprintf(&b, "+ permission %s\n", name)
+	}
+	for _, name := range d.RemovedPermissions {
+		fmt.Fprintf(&b, "- permission %s\n", name)
+	}
+	for _, p := range d.ChangedPermissions {
+		fmt.Fprintf(&b, "~ permission %s description %q -> %q\n", p.Name, p.DescriptionFrom, p.DescriptionTo)
+	}
+	for _, edge := range d.AddedImplications {
+		fmt.Fprintf(&b, "+ implication %s\n", edge)
+	}
+	for _, edge := range d.RemovedImplications {
+		fmt.Fprintf(&b, "- implication %s\n", edge)
+	}
+
+	return b.String()
+}
+
+// diffNames returns the sorted names only present in the second list and only present in the first
+func diffNames(from, to []string) (added, removed []string) {
+	inFrom := map[string]bool{}
+	for _, name := range from {
+		inFrom[name] = true
+	}
+	inTo := map[string]bool{}
+	for _, name := range to {
+		inTo[name] = true
+		if !inFrom[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range from {
+		if !inTo[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	return added, removed
+}
+
+// implicationEdges flattens the implications to "permission -> implied permission" edges
+func implicationEdges(graph map[string][]string) []string {
+	var edges []string
+	for perm, implied := range graph {
+		for _, name := range implied {
+			edges = append(edges, perm+" -> "+name)
+		}
+	}
+
+	return edges
+}
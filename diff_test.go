@@ -0,0 +1,56 @@
+package authority_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	staging := &authority.PolicyExport{
+		Roles: []authority.ExportedRole{
+			{Name: "role-a", Description: "a", Permissions: []string{"permission-a", "permission-b"}},
+			{Name: "role-b", Description: "b"},
+		},
+		Permissions: []authority.ExportedPermission{
+			{Name: "permission-a", Description: "a"},
+			{Name: "permission-b", Description: "b"},
+		},
+		Implications: map[string][]string{"permission-a": {"permission-b"}},
+	}
+	production := &authority.PolicyExport{
+		Roles: []authority.ExportedRole{
+			{Name: "role-a", Description: "a", Permissions: []string{"permission-a", "permission-c"}},
+			{Name: "role-c", Description: "c"},
+		},
+		Permissions: []authority.ExportedPermission{
+			{Name: "permission-a", Description: "the a"},
+			{Name: "permission-c", Description: "c"},
+		},
+	}
+
+	if !authority.DiffPolicies(staging, staging).Empty() {
+		t.Error("expecting no differences between the same exports")
+	}
+
+	d := authority.DiffPolicies(staging, production)
+	if len(d.AddedRoles) != 1 || d.AddedRoles[0] != "role-c" {
+		t.Error("expecting the added role")
+	}
+	if len(d.RemovedRoles) != 1 || d.RemovedRoles[0] != "role-b" {
+		t.Error("expecting the removed role")
+	}
+	if len(d.ChangedRoles) != 1 || d.ChangedRoles[0].AddedPermissions[0] != "permission-c" || d.ChangedRoles[0].RemovedPermissions[0] != "permission-b" {
+		t.Errorf("unexpected changed roles %+v", d.ChangedRoles)
+	}
+	if len(d.ChangedPermissions) != 1 || d.ChangedPermissions[0].DescriptionTo != "the a" {
+		t.Error("expecting the changed permission description")
+	}
+	if len(d.RemovedImplications) != 1 || d.RemovedImplications[0] != "permission-a -> permission-b" {
+		t.Error("expecting the removed implication")
+	}
+	if !strings.Contains(d.String(), "- role role-b\n") || !strings.Contains(d.String(), "+ role role-a permission permission-c\n") {
+		t.Errorf("unexpected readable diff\n%s", d)
+	}
+}
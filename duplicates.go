@@ -0,0 +1,154 @@
+package authority
+
+import (
+	"gorm.io/gorm"
+)
+
+// duplicatedName is a name shared by several records along with the oldest one
+type duplicatedName struct {
+	Name string
+	Keep uint
+}
+
+// mergeDuplicateNames merges the roles and the permissions sharing a name into the oldest
+// one so the unique indexes of the names can be created on an existing database
+// the permissions and the implications of the duplicates are moved to the kept records,
+// the role assignments are moved as well and the duplicated ones are left to RepairAssignments
+// it returns the number of removed duplicates
+func (a *Authority) mergeDuplicateNames() (int64, error) {
+	var removed int64
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if tx.Migrator().HasTable(a.tableName(&Role{})) {
+			n, err := mergeDuplicates(tx, &Role{}, func(keep uint, ids []uint) error {
+				if err := a.moveRows(tx, &RolePermission{}, "role_id", keep, ids); err != nil {
+					return err
+				}
+				if err := a.moveRows(tx, &UserRole{}, "role_id", keep, ids); err != nil {
+					return err
+				}
+				return a.moveRows(tx, &Role{}, "managed_by_role_id", keep, ids)
+			})
+			if err != nil {
+				return err
+			}
+			removed += n
+		}
+		if tx.Migrator().HasTable(a.tableName(&Permission{})) {
+			n, err := mergeDuplicates(tx, &Permission{}, func(keep uint, ids []uint) error {
+				if err := a.moveRows(tx, &RolePermission{}, "permission_id", keep, ids); err != nil {
+					return err
+				}
+				if err := a.moveRows(tx, &PermissionImplication{}, "permission_id", keep, ids); err != nil {
+					return err
+				}
+				return a.moveRows(tx, &PermissionImplication{}, "implied_permission_id", keep, ids)
+			})
+			if err != nil {
+				return err
+			}
+			removed += n
+		}
+		if removed == 0 {
+			return nil
+		}
+		if err := a.dropDuplicatedRolePermissions(tx); err != nil {
+			return err
+		}
+		return a.dropDuplicatedImplications(tx)
+	})
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	return removed, nil
+}
+
+// mergeDuplicates calls move for every name shared by several records of the model
+// then deletes all of them but the oldest one, it returns the number of deleted records
+func mergeDuplicates(tx *gorm.DB, m model, move func(keep uint, ids []uint) error) (int64, error) {
+	var names []duplicatedName
+	res := tx.Model(m).Select("name, MIN(id) AS keep").Group("name").Having("COUNT(*) > 1").Scan(&names)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+
+	var removed int64
+	for _, n := range names {
+		var ids []uint
+		if res := tx.Model(m).Where("name = ?", n.Name).Where("id <> ?", n.Keep).Pluck("id", &ids); res.Error != nil {
+			return 0, res.Error
+		}
+		if err := move(n.Keep, ids); err != nil {
+			return 0, err
+		}
+		res := tx.Where("id IN (?)", ids).Delete(m)
+		if res.Error != nil {
+			return 0, res.Error
+		}
+		removed += res.RowsAffected
+	}
+
+	return removed, nil
+}
+
+// moveRows points the column of the rows referencing the duplicates to the kept record
+func (a *Authority) moveRows(tx *gorm.DB, m model, column string, keep uint, ids []uint) error {
+	if !tx.Migrator().HasTable(a.tableName(m)) {
+		return nil
+	}
+
+	return tx.Model(m).Where(column+" IN (?)", ids).Update(column, keep).Error
+}
+
+// dropDuplicatedRolePermissions deletes the permissions assigned twice to a role by the merge
+func (a *Authority) dropDuplicatedRolePermissions(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(a.tableName(&RolePermission{})) {
+		return nil
+	}
+	var rolePerms []RolePermission
+	if res := tx.Order("id").Find(&rolePerms); res.Error != nil {
+		return res.Error
+	}
+	type assignment struct{ roleID, permissionID uint }
+	seen := map[assignment]bool{}
+	var ids []uint
+	for _, rp := range rolePerms {
+		key := assignment{rp.RoleID, rp.PermissionID}
+		if seen[key] {
+			ids = append(ids, rp.ID)
+		}
+		seen[key] = true
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.Where("id IN (?)", ids).Delete(RolePermission{}).Error
+}
+
+// dropDuplicatedImplications deletes the implications declared twice or made
+// of a single permission by the merge
+func (a *Authority) dropDuplicatedImplications(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(a.tableName(&PermissionImplication{})) {
+		return nil
+	}
+	var edges []PermissionImplication
+	if res := tx.Order("id").Find(&edges); res.Error != nil {
+		return res.Error
+	}
+	type edge struct{ from, to uint }
+	seen := map[edge]bool{}
+	var ids []uint
+	for _, e := range edges {
+		key := edge{e.PermissionID, e.ImpliedPermissionID}
+		if seen[key] || e.PermissionID == e.ImpliedPermissionID {
+			ids = append(ids, e.ID)
+		}
+		seen[key] = true
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.Where("id IN (?)", ids).Delete(PermissionImplication{}).Error
+}
@@ -0,0 +1,80 @@
+package authority_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestMergeDuplicateNames(t *testing.T) {
+	defer authority.New(authority.Options{TablesPrefix: "authority_", DB: db})
+	dropTables := func() {
+		var tables []string
+		db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name LIKE ?", "dupes\\_%").Scan(&tables)
+		for _, table := range tables {
+			db.Migrator().DropTable(table)
+		}
+	}
+	defer dropTables()
+
+	// a database migrated before the names were unique
+	authority.New(authority.Options{TablesPrefix: "dupes_", DB: db})
+	db.Exec("DROP INDEX idx_dupes_roles_name ON dupes_roles")
+	db.Exec("DROP INDEX idx_dupes_permissions_name ON dupes_permissions")
+	roles := []authority.Role{{Name: "role-a"}, {Name: "role-a"}}
+	db.Table("dupes_roles").Create(&roles)
+	perms := []authority.Permission{{Name: "permission-a"}, {Name: "permission-a"}}
+	db.Table("dupes_permissions").Create(&perms)
+	db.Table("dupes_role_permissions").Create(&[]authority.RolePermission{
+		{RoleID: roles[0].ID, PermissionID: perms[0].ID},
+		{RoleID: roles[1].ID, PermissionID: perms[1].ID},
+	})
+	userID := uuid.New()
+	db.Table("dupes_user_roles").Create(&authority.UserRole{UserID: userID, RoleID: roles[1].ID})
+
+	// the creates are refused without the unique indexes
+	auth := authority.New(authority.Options{TablesPrefix: "dupes_", DB: db})
+	if err := auth.CreateRole("role-b", "b description role"); !errors.Is(err, authority.ErrDuplicateNames) {
+		t.Error("expecting the creates to be refused without the unique indexes.", err)
+	}
+	if err := auth.CreatePermission("permission-b", "b description permission"); !errors.Is(err, authority.ErrDuplicateNames) {
+		t.Error("expecting the creates to be refused without the unique indexes.", err)
+	}
+	_, err := authority.NewWithError(authority.Options{TablesPrefix: "dupes_", DB: db})
+	if !errors.Is(err, authority.ErrDuplicateNames) {
+		t.Error("expecting an error when the unique indexes cannot be created.", err)
+	}
+
+	// the duplicates are merged into the oldest records
+	auth, err = authority.NewWithError(authority.Options{TablesPrefix: "dupes_", DB: db, MergeDuplicateNames: true})
+	if err != nil {
+		t.Fatal("unexpected error while merging the duplicated names.", err)
+	}
+	var count int64
+	db.Table("dupes_roles").Where("name = ?", "role-a").Count(&count)
+	if count != 1 {
+		t.Errorf("expecting a single role-a, got %d", count)
+	}
+	db.Table("dupes_permissions").Where("name = ?", "permission-a").Count(&count)
+	if count != 1 {
+		t.Errorf("expecting a single permission-a, got %d", count)
+	}
+	var rolePerms []authority.RolePermission
+	db.Table("dupes_role_permissions").Find(&rolePerms)
+	if len(rolePerms) != 1 || rolePerms[0].RoleID != roles[0].ID || rolePerms[0].PermissionID != perms[0].ID {
+		t.Errorf("expecting the permissions to be merged, got %+v", rolePerms)
+	}
+	ok, err := auth.CheckPermission(userID, "permission-a")
+	if err != nil || !ok {
+		t.Error("expecting the assignments of the duplicates to be kept.", err)
+	}
+	if err := auth.CreateRole("role-a", "a description role"); err != nil {
+		t.Error("unexpected error while creating a role after the merge.", err)
+	}
+	db.Table("dupes_roles").Where("name = ?", "role-a").Count(&count)
+	if count != 1 {
+		t.Error("expecting the unique index to be created by the merge")
+	}
+}
@@ -0,0 +1,111 @@
+package authority
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Encryptor encrypts the sensitive free text fields, like the notes of the assignments,
+// before they are written to the database
+type Encryptor interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// sealedPrefix marks the encrypted values so the values written before the encryptor
+// was set are still readable
+const sealedPrefix = "enc:"
+
+var errCiphertext = errors.New("malformed ciphertext")
+
+// SetEncryptor sets the encryptor of the sensitive fields, nil stores them in clear
+func (a *Authority) SetEncryptor(e Encryptor) {
+	a.encMu.Lock()
+	defer a.encMu.Unlock()
+	a.encryptor = e
+}
+
+// seal encrypts a sensitive value if an encryptor is set
+func (a *Authority) seal(value string) (string, error) {
+	a.encMu.RLock()
+	e := a.encryptor
+	a.encMu.RUnlock()
+	if e == nil || value == "" {
+		return value, nil
+	}
+
+	ciphertext, err := e.Encrypt(value)
+	if err != nil {
+		return "", err
+	}
+
+	return sealedPrefix + ciphertext, nil
+}
+
+// open decrypts a sensitive value sealed by seal, the values in clear are returned as is
+func (a *Authority) open(value string) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+
+	a.encMu.RLock()
+	e := a.encryptor
+	a.encMu.RUnlock()
+	if e == nil {
+		return "", errors.New("an encrypted value cannot be read without an encryptor")
+	}
+
+	return e.Decrypt(strings.TrimPrefix(value, sealedPrefix))
+}
+
+// aesEncryptor encrypts with AES-GCM
+type aesEncryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESEncryptor returns an encryptor using AES-GCM with the given 16, 24 or 32 bytes key
+// the nonce is random and stored along with the base64 ciphertext
+func NewAESEncryptor(key []byte) (Encryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesEncryptor{aead: aead}, nil
+}
+
+func (e *aesEncryptor) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := e.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (e *aesEncryptor) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < e.aead.NonceSize() {
+		return "", errCiphertext
+	}
+	nonce, sealed := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+	plaintext, err := e.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
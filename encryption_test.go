@@ -0,0 +1,38 @@
+package authority_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/faozimipa/authority"
+)
+
+func TestAESEncryptor(t *testing.T) {
+	e, err := authority.NewAESEncryptor(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Error("unexpected error while creating encryptor.", err)
+	}
+
+	ciphertext, err := e.Encrypt("ticket OPS-1 approved by the on call")
+	if err != nil {
+		t.Error("unexpected error while encrypting.", err)
+	}
+	if bytes.Contains([]byte(ciphertext), []byte("OPS-1")) {
+		t.Error("expecting the value to be encrypted")
+	}
+	plaintext, err := e.Decrypt(ciphertext)
+	if err != nil || plaintext != "ticket OPS-1 approved by the on call" {
+		t.Error("expecting the value to be decrypted", err)
+	}
+
+	other, _ := authority.NewAESEncryptor(bytes.Repeat([]byte("o"), 32))
+	_, err = other.Decrypt(ciphertext)
+	if err == nil {
+		t.Error("expecting an error when decrypting with another key")
+	}
+
+	_, err = authority.NewAESEncryptor([]byte("short"))
+	if err == nil {
+		t.Error("expecting an error for an invalid key size")
+	}
+}
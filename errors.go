@@ -0,0 +1,158 @@
+package authority
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorCode is a stable machine readable identifier of a failure
+type ErrorCode int
+
+// the error codes returned by the package
+const (
+	CodeUnknown ErrorCode = iota
+	CodeRoleNotFound
+	CodePermissionNotFound
+	CodeRoleInUse
+	CodePermissionInUse
+	CodeConflict
+	CodeStoreUnavailable
+	CodeFederationUnavailable
+	CodeForbidden
+	CodeNamespaceNotFound
+	CodeJobNotFound
+	CodePolicyFrozen
+	CodeNothingToRevoke
+	CodeMutationRejected
+	CodeReadOnly
+	CodeInvalidArgument
+)
+
+var codeNames = map[ErrorCode]string{
+	CodeUnknown:               "unknown",
+	CodeRoleNotFound:          "role_not_found",
+	CodePermissionNotFound:    "permission_not_found",
+	CodeRoleInUse:             "role_in_use",
+	CodePermissionInUse:       "permission_in_use",
+	CodeConflict:              "conflict",
+	CodeStoreUnavailable:      "store_unavailable",
+	CodeFederationUnavailable: "federation_unavailable",
+	CodeForbidden:             "forbidden",
+	CodeNamespaceNotFound:     "namespace_not_found",
+	CodeJobNotFound:           "job_not_found",
+	CodePolicyFrozen:          "policy_frozen",
+	CodeNothingToRevoke:       "nothing_to_revoke",
+	CodeMutationRejected:      "mutation_rejected",
+	CodeReadOnly:              "read_only",
+	CodeInvalidArgument:       "invalid_argument",
+}
+
+// String returns the name of the code, it's suitable as a translation key
+func (c ErrorCode) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+
+	return codeNames[CodeUnknown]
+}
+
+// HTTPStatus returns the http status matching the code
+func (c ErrorCode) HTTPStatus() int {
+	switch c {
+	case CodeRoleNotFound, CodePermissionNotFound, CodeNamespaceNotFound, CodeJobNotFound, CodeNothingToRevoke:
+		return http.StatusNotFound
+	case CodeRoleInUse, CodePermissionInUse, CodeConflict:
+		return http.StatusConflict
+	case CodeStoreUnavailable, CodeFederationUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeForbidden, CodeReadOnly:
+		return http.StatusForbidden
+	case CodePolicyFrozen:
+		return http.StatusLocked
+	case CodeMutationRejected:
+		return http.StatusTooManyRequests
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// AuthorityError is the error returned by the package
+// it carries a stable code and wraps the underlying cause if any
+type AuthorityError struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+// Error returns the error message
+func (e *AuthorityError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+// Unwrap returns the underlying cause
+func (e *AuthorityError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is the same kind of error regardless of the cause
+func (e *AuthorityError) Is(target error) bool {
+	t, ok := target.(*AuthorityError)
+	return ok && t.Code == e.Code && t.Message == e.Message
+}
+
+var (
+	ErrPermissionInUse         = &AuthorityError{Code: CodePermissionInUse, Message: "cannot delete assigned permission"}
+	ErrPermissionNotFound      = &AuthorityError{Code: CodePermissionNotFound, Message: "permission not found"}
+	ErrRoleAlreadyAssigned     = &AuthorityError{Code: CodeConflict, Message: "this role is already assigned to the user"}
+	ErrRoleInUse               = &AuthorityError{Code: CodeRoleInUse, Message: "cannot delete assigned role"}
+	ErrRoleNotFound            = &AuthorityError{Code: CodeRoleNotFound, Message: "role not found"}
+	ErrRoleNameConflict        = &AuthorityError{Code: CodeConflict, Message: "a role with the same name already exists"}
+	ErrPermissionNameConflict  = &AuthorityError{Code: CodeConflict, Message: "a permission with the same name already exists"}
+	ErrStoreUnavailable        = &AuthorityError{Code: CodeStoreUnavailable, Message: "the store could not be reached"}
+	ErrFederationUnavailable   = &AuthorityError{Code: CodeFederationUnavailable, Message: "the owning service of the permission could not be reached"}
+	ErrImplicationCycle        = &AuthorityError{Code: CodeConflict, Message: "the permission implication would create a cycle"}
+	ErrModuleConflict          = &AuthorityError{Code: CodeConflict, Message: "the artifact is owned by another module"}
+	ErrNamespaceNotFound       = &AuthorityError{Code: CodeNamespaceNotFound, Message: "namespace not found"}
+	ErrJobNotFound             = &AuthorityError{Code: CodeJobNotFound, Message: "job not found"}
+	ErrJobNotResumable         = &AuthorityError{Code: CodeConflict, Message: "the job cannot be resumed"}
+	ErrPartitioningUnsupported = &AuthorityError{Code: CodeInvalidArgument, Message: "partitioning is not supported by the database"}
+	ErrInvalidPartitions       = &AuthorityError{Code: CodeInvalidArgument, Message: "expecting at least two partitions"}
+	ErrPolicyFrozen            = &AuthorityError{Code: CodePolicyFrozen, Message: "the policy is frozen, mutations are rejected"}
+	ErrNothingToRevoke         = &AuthorityError{Code: CodeNothingToRevoke, Message: "nothing was assigned to be revoked"}
+	ErrMutationRejected        = &AuthorityError{Code: CodeMutationRejected, Message: "the mutation was rejected by the hook"}
+	ErrPrefixConflict          = &AuthorityError{Code: CodeConflict, Message: "a table with the new prefix already exists"}
+	ErrReadOnly                = &AuthorityError{Code: CodeReadOnly, Message: "the instance is read only, mutations are rejected"}
+	ErrInvalidOptions          = &AuthorityError{Code: CodeInvalidArgument, Message: "invalid options"}
+	ErrForbidden               = &AuthorityError{Code: CodeForbidden, Message: "the principal is not allowed to perform this operation"}
+	ErrDuplicateNames          = &AuthorityError{Code: CodeConflict, Message: "the role or permission names are not unique"}
+)
+
+// ErrorCodeOf returns the code of the error, CodeUnknown if it's not returned by the package
+func ErrorCodeOf(err error) ErrorCode {
+	var e *AuthorityError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return CodeUnknown
+}
+
+// wrapError returns an error of the same kind as the given one wrapping the cause
+func wrapError(kind *AuthorityError, cause error) error {
+	return &AuthorityError{Code: kind.Code, Message: kind.Message, Err: cause}
+}
+
+// storeError wraps the database errors, it returns nil if there is no error
+func storeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return wrapError(ErrStoreUnavailable, err)
+}
@@ -0,0 +1,52 @@
+package authority_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestErrorCodes(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	err := auth.AssignRole(uuid.New(), "role-aa")
+	if authority.ErrorCodeOf(err) != authority.CodeRoleNotFound {
+		t.Error("expecting role not found code")
+	}
+	if authority.ErrorCodeOf(err).HTTPStatus() != http.StatusNotFound {
+		t.Error("expecting not found status")
+	}
+	if authority.ErrorCodeOf(err).String() != "role_not_found" {
+		t.Error("unexpected code name")
+	}
+
+	// wrapped causes keep the kind of the error
+	cause := errors.New("connection refused")
+	err = &authority.AuthorityError{Code: authority.CodeStoreUnavailable, Message: authority.ErrStoreUnavailable.Message, Err: cause}
+	if !errors.Is(err, authority.ErrStoreUnavailable) {
+		t.Error("expecting the wrapped error to match its kind")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("expecting the wrapped error to match its cause")
+	}
+	if errors.Is(authority.ErrRoleAlreadyAssigned, authority.ErrStoreUnavailable) {
+		t.Error("unexpected match between different kinds of errors")
+	}
+	if authority.ErrorCodeOf(cause) != authority.CodeUnknown {
+		t.Error("expecting unknown code for foreign errors")
+	}
+
+	// the caller errors map to bad request
+	for _, err := range []error{authority.ErrInvalidOptions, authority.ErrInvalidPartitions, authority.ErrPartitioningUnsupported} {
+		code := authority.ErrorCodeOf(err)
+		if code != authority.CodeInvalidArgument || code.HTTPStatus() != http.StatusBadRequest || code.String() != "invalid_argument" {
+			t.Errorf("expecting invalid argument for %v", err)
+		}
+	}
+}
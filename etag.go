@@ -0,0 +1,109 @@
+package authority
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PolicyVersion identifies the state of the policy, it changes with every mutation
+type PolicyVersion struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// tableVersion aggregates the rows of a table
+type tableVersion struct {
+	Count    int64
+	MaxID    uint
+	Modified sql.NullTime
+}
+
+// GetPolicyVersion returns the version of the stored policy
+// it's derived from aggregates of the policy tables and the assignment history
+// so it's shared by all the instances using the same database
+func (a *Authority) GetPolicyVersion() (PolicyVersion, error) {
+	queries := []struct {
+		model   interface{}
+		columns string
+	}{
+		{&Role{}, "COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id, MAX(updated_at) AS modified"},
+		{&Permission{}, "COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id, MAX(updated_at) AS modified"},
+		{&PermissionImplication{}, "COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id"},
+		{&PermissionNamespace{}, "COUNT(*) AS count, COALESCE(MAX(id), 0) AS max_id"},
+		// the revokes are recorded as events, only the last id of the assignments is needed
+		{&UserRole{}, "COALESCE(MAX(id), 0) AS max_id"},
+		{&AssignmentEvent{}, "COALESCE(MAX(id), 0) AS max_id, MAX(created_at) AS modified"},
+	}
+
+	var v PolicyVersion
+	h := sha1.New()
+	for _, q := range queries {
+		var tv tableVersion
+		if res := a.DB.Model(q.model).Select(q.columns).Scan(&tv); res.Error != nil {
+			return v, storeError(res.Error)
+		}
+		fmt.Fprintf(h, "%d:%d:%d;", tv.Count, tv.MaxID, tv.Modified.Time.UnixNano())
+		if tv.Modified.Valid && tv.Modified.Time.After(v.LastModified) {
+			v.LastModified = tv.Modified.Time
+		}
+	}
+	v.ETag = `"` + hex.EncodeToString(h.Sum(nil))[:16] + `"`
+
+	return v, nil
+}
+
+// ConditionalMiddleware sets the ETag and Last-Modified headers of the policy version on the
+// GET and HEAD requests and answers 304 Not Modified to the matching conditional requests,
+// it's meant to wrap the admin handlers polled by the admin UIs
+func (a *Authority) ConditionalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		v, err := a.GetPolicyVersion()
+		if err != nil {
+			a.logf("authority: policy version: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("ETag", v.ETag)
+		if !v.LastModified.IsZero() {
+			w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+		}
+		if notModified(r, v) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// notModified reports whether the conditional request matches the version
+// If-None-Match takes precedence over If-Modified-Since
+func notModified(r *http.Request, v PolicyVersion) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == v.ETag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !v.LastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !v.LastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
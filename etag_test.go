@@ -0,0 +1,62 @@
+package authority_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/faozimipa/authority"
+)
+
+func TestConditionalMiddleware(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	served := 0
+	handler := auth.ConditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.Write([]byte("roles"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/roles", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Error("expecting the etag to be set")
+	}
+
+	// the same version is not served again
+	req := httptest.NewRequest(http.MethodGet, "/roles", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || served != 1 {
+		t.Error("expecting not modified for the same version")
+	}
+
+	// a mutation changes the version
+	auth.CreateRole("role-a", "a description role")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Error("expecting a new version after a mutation")
+	}
+	if rec.Header().Get("Last-Modified") == "" {
+		t.Error("expecting the last modified header")
+	}
+	etag = rec.Header().Get("ETag")
+
+	auth.UpdateRoleByName("role-a", "role-a", "another description role")
+	v, err := auth.GetPolicyVersion()
+	if err != nil {
+		t.Error("unexpected error while getting policy version.", err)
+	}
+	if v.ETag == etag {
+		t.Error("expecting a new version after an update")
+	}
+
+	// clean up
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
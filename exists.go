@@ -0,0 +1,33 @@
+package authority
+
+// RolesExist reports for every given name whether a role with that name exists
+// the names are checked with a single query
+func (a *Authority) RolesExist(roleNames []string) (map[string]bool, error) {
+	return a.namesExist(Role{}, roleNames)
+}
+
+// PermissionsExist reports for every given name whether a permission with that name exists
+// the names are checked with a single query
+func (a *Authority) PermissionsExist(permNames []string) (map[string]bool, error) {
+	return a.namesExist(Permission{}, permNames)
+}
+
+func (a *Authority) namesExist(model interface{}, names []string) (map[string]bool, error) {
+	result := map[string]bool{}
+	for _, name := range names {
+		result[name] = false
+	}
+	if len(names) == 0 {
+		return result, nil
+	}
+
+	var found []string
+	if res := a.DB.Model(model).Where("name IN (?)", names).Pluck("name", &found); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+	for _, name := range found {
+		result[name] = true
+	}
+
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package authority_test
+
+import (
+	"testing"
+
+	"github.com/faozimipa/authority"
+)
+
+func TestRolesExist(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	exist, err := auth.RolesExist([]string{"role-a", "role-x"})
+	if err != nil {
+		t.Error("unexpected error while checking roles existence.", err)
+	}
+	if len(exist) != 2 || !exist["role-a"] || exist["role-x"] {
+		t.Errorf("unexpected result %v", exist)
+	}
+
+	// clean up
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestPermissionsExist(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreatePermission("permission-a", "a description permission")
+	exist, err := auth.PermissionsExist([]string{"permission-a", "permission-x"})
+	if err != nil {
+		t.Error("unexpected error while checking permissions existence.", err)
+	}
+	if len(exist) != 2 || !exist["permission-a"] || exist["permission-x"] {
+		t.Errorf("unexpected result %v", exist)
+	}
+
+	// clean up
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+}
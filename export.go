@@ -0,0 +1,70 @@
+package authority
+
+import (
+	"sort"
+)
+
+// PolicyExport is a snapshot of the roles, permissions and implications of an environment
+// the user assignments are not part of the policy
+type PolicyExport struct {
+	Roles        []ExportedRole       `json:"roles"`
+	Permissions  []ExportedPermission `json:"permissions"`
+	Implications map[string][]string  `json:"implications,omitempty"`
+}
+
+// ExportedRole is a role of a policy export along with the names of its permissions
+type ExportedRole struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// ExportedPermission is a permission of a policy export
+type ExportedPermission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ExportPolicy returns a snapshot of the stored policy, the roles, permissions
+// and implications are sorted by name so exports of equal policies are equal
+func (a *Authority) ExportPolicy() (*PolicyExport, error) {
+	var roles []Role
+	if res := a.DB.Order("name").Find(&roles); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+	var perms []Permission
+	if res := a.DB.Order("name").Find(&perms); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+	var rolePerms []RolePermission
+	if res := a.DB.Find(&rolePerms); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+	graph, err := a.GetPermissionGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	permNames := map[uint]string{}
+	export := &PolicyExport{Roles: []ExportedRole{}, Permissions: []ExportedPermission{}, Implications: graph}
+	for _, p := range perms {
+		permNames[p.ID] = p.Name
+		export.Permissions = append(export.Permissions, ExportedPermission{Name: p.Name, Description: p.Description})
+	}
+	rolePermNames := map[uint][]string{}
+	for _, rp := range rolePerms {
+		if name, found := permNames[rp.PermissionID]; found {
+			rolePermNames[rp.RoleID] = append(rolePermNames[rp.RoleID], name)
+		}
+	}
+	for _, r := range roles {
+		names := append([]string{}, rolePermNames[r.ID]...)
+		sort.Strings(names)
+		export.Roles = append(export.Roles, ExportedRole{Name: r.Name, Description: r.Description, Permissions: names})
+	}
+	for _, implied := range graph {
+		sort.Strings(implied)
+	}
+
+	return export, nil
+}
@@ -0,0 +1,48 @@
+package authority_test
+
+import (
+	"testing"
+
+	"github.com/faozimipa/authority"
+)
+
+func TestExportPolicy(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.CreatePermission("permission-b", "b description permission")
+	auth.AssignPermissions("role-a", []string{"permission-b", "permission-a"})
+	auth.AddPermissionImplication("permission-a", "permission-b")
+
+	export, err := auth.ExportPolicy()
+	if err != nil {
+		t.Error("unexpected error while exporting policy.", err)
+	}
+	var role *authority.ExportedRole
+	for i := range export.Roles {
+		if export.Roles[i].Name == "role-a" {
+			role = &export.Roles[i]
+		}
+	}
+	if role == nil {
+		t.Fatal("expecting the role to be exported")
+	}
+	if len(role.Permissions) != 2 || role.Permissions[0] != "permission-a" || role.Permissions[1] != "permission-b" {
+		t.Error("expecting the sorted role permissions to be exported")
+	}
+	if !sliceHasString(export.Implications["permission-a"], "permission-b") {
+		t.Error("expecting the implications to be exported")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	auth.RemovePermissionImplication("permission-a", "permission-b")
+	db.Where("name IN (?)", []string{"permission-a", "permission-b"}).Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
@@ -0,0 +1,278 @@
+package authority
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// federationRequest is the payload sent by a federation client
+type federationRequest struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Permission string    `json:"permission"`
+	Tenant     string    `json:"tenant,omitempty"`
+}
+
+// federationResponse is the payload returned by a federation server
+type federationResponse struct {
+	Allowed bool   `json:"allowed"`
+	Error   string `json:"error,omitempty"`
+}
+
+const federationPermissionNotFound = "permission_not_found"
+
+// FederationAuthorizer authorizes the caller of a delegated check of a user permission
+// it returns an error to reject the request
+type FederationAuthorizer func(r *http.Request, userID uuid.UUID, permName string) error
+
+// AllowAnyCaller is the authorizer of the servers reachable by trusted callers only
+func AllowAnyCaller(r *http.Request, userID uuid.UUID, permName string) error {
+	return nil
+}
+
+// FederationServer answers permission checks delegated by other services
+// for the resource types owned by this service
+type FederationServer struct {
+	auth          *Authority
+	resourceTypes map[string]bool
+
+	mu        sync.RWMutex
+	authorize FederationAuthorizer
+}
+
+// NewFederationServer returns an http handler that answers delegated checks
+// it accepts the authority instance as the first parameter, the authorizer of
+// the callers as the second and the owned resource types as the rest,
+// if no resource types are passed all permissions are answered
+// a nil authorizer rejects every caller, see AllowAnyCaller
+func NewFederationServer(a *Authority, authorize FederationAuthorizer, resourceTypes ...string) *FederationServer {
+	s := &FederationServer{auth: a, resourceTypes: map[string]bool{}, authorize: authorize}
+	for _, rt := range resourceTypes {
+		s.resourceTypes[rt] = true
+	}
+
+	return s
+}
+
+// SetAuthorizer sets the authorizer of the callers, the rejected requests
+// are answered with 403 Forbidden, nil rejects every caller
+func (s *FederationServer) SetAuthorizer(authorize FederationAuthorizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorize = authorize
+}
+
+// ServeHTTP handles a delegated permission check
+func (s *FederationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req federationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if len(s.resourceTypes) > 0 && !s.resourceTypes[resourceType(req.Permission)] {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.mu.RLock()
+	authorize := s.authorize
+	s.mu.RUnlock()
+	if authorize == nil || authorize(r, req.UserID, req.Permission) != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var res federationResponse
+	ok, err := s.auth.checkLocalPermission(WithTenant(r.Context(), req.Tenant), req.UserID, req.Permission)
+	if err != nil {
+		if !errors.Is(err, ErrPermissionNotFound) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		res.Error = federationPermissionNotFound
+	}
+	res.Allowed = ok
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// FederationClientOptions has the options for initiating a federation client
+type FederationClientOptions struct {
+	// URL is the address of the owning service federation server
+	URL string
+	// Timeout bounds every delegated check, defaults to 2 seconds
+	// it applies to the http client as well
+	Timeout time.Duration
+	// CacheTTL is how long a delegated result is reused, zero disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the default http client
+	HTTPClient *http.Client
+	// Clock is the time source of the cache expiry, defaults to the clock
+	// of the federating instance
+	Clock Clock
+}
+
+// FederationClient delegates permission checks to the owning service
+type FederationClient struct {
+	url      string
+	client   *http.Client
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	clock Clock
+	cache map[string]federationCacheEntry
+}
+
+type federationCacheEntry struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// NewFederationClient initiates a federation client
+func NewFederationClient(opts FederationClientOptions) *FederationClient {
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	return &FederationClient{
+		url:      opts.URL,
+		client:   client,
+		timeout:  timeout,
+		cacheTTL: opts.CacheTTL,
+		clock:    opts.Clock,
+		cache:    map[string]federationCacheEntry{},
+	}
+}
+
+// CheckPermission asks the owning service if the user has the permission
+// it returns ErrPermissionNotFound if the permission is unknown to the owning service
+// it returns ErrFederationUnavailable if the owning service could not be reached
+func (c *FederationClient) CheckPermission(userID uuid.UUID, permName string) (bool, error) {
+	return c.CheckPermissionContext(context.Background(), userID, permName)
+}
+
+// CheckPermissionContext asks the owning service if the user has the permission
+// within the tenant of the context
+func (c *FederationClient) CheckPermissionContext(ctx context.Context, userID uuid.UUID, permName string) (bool, error) {
+	tenant := TenantFromContext(ctx)
+	key := userID.String() + "|" + tenant + "|" + permName
+	now := c.now()
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		entry, found := c.cache[key]
+		c.mu.Unlock()
+		if found && now.Before(entry.expiresAt) {
+			return entry.allowed, nil
+		}
+	}
+
+	// the timeout is applied per request so it bounds the checks made with any http client
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	body, _ := json.Marshal(federationRequest{UserID: userID, Permission: permName, Tenant: tenant})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, wrapError(ErrFederationUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, wrapError(ErrFederationUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, wrapError(ErrFederationUnavailable, fmt.Errorf("unexpected status %d", resp.StatusCode))
+	}
+
+	var res federationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return false, wrapError(ErrFederationUnavailable, err)
+	}
+	if res.Error == federationPermissionNotFound {
+		return false, ErrPermissionNotFound
+	}
+
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		c.cache[key] = federationCacheEntry{allowed: res.Allowed, expiresAt: now.Add(c.cacheTTL)}
+		c.mu.Unlock()
+	}
+
+	return res.Allowed, nil
+}
+
+// Flush drops all cached delegated results
+func (c *FederationClient) Flush() {
+	c.mu.Lock()
+	c.cache = map[string]federationCacheEntry{}
+	c.mu.Unlock()
+}
+
+// now returns the current time of the client clock
+func (c *FederationClient) now() time.Time {
+	c.mu.Lock()
+	clock := c.clock
+	c.mu.Unlock()
+	if clock == nil {
+		return time.Now()
+	}
+
+	return clock.Now()
+}
+
+// Federate delegates the checks of the given resource type to a federation client
+// the resource type is the part of the permission name before the first dot
+// for example "billing" for "billing.invoices.view"
+// the client without a clock uses the clock of the instance
+func (a *Authority) Federate(resourceType string, client *FederationClient) {
+	client.mu.Lock()
+	if client.clock == nil && a.clock != nil {
+		client.clock = a.clock
+	}
+	client.mu.Unlock()
+
+	a.fedMu.Lock()
+	defer a.fedMu.Unlock()
+	if a.federation == nil {
+		a.federation = map[string]*FederationClient{}
+	}
+	a.federation[resourceType] = client
+}
+
+// federationClient returns the client owning the permission resource type if any
+func (a *Authority) federationClient(permName string) *FederationClient {
+	a.fedMu.RLock()
+	defer a.fedMu.RUnlock()
+	return a.federation[resourceType(permName)]
+}
+
+// resourceType returns the part of the permission name before the first dot
+func resourceType(permName string) string {
+	if i := strings.Index(permName, "."); i >= 0 {
+		return permName[:i]
+	}
+
+	return permName
+}
@@ -0,0 +1,207 @@
+package authority_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestFederation(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	// the owning service
+	srv := httptest.NewServer(authority.NewFederationServer(auth, authority.AllowAnyCaller, "billing"))
+	defer srv.Close()
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("billing.invoices.view", "a description permission")
+	auth.AssignPermissions("role-a", []string{"billing.invoices.view"})
+	id := uuid.New()
+	auth.AssignRole(id, "role-a")
+
+	// the delegating service
+	client := authority.NewFederationClient(authority.FederationClientOptions{
+		URL:      srv.URL,
+		CacheTTL: time.Minute,
+	})
+	fed := &authority.Authority{DB: db}
+	fed.Federate("billing", client)
+
+	ok, err := fed.CheckPermission(id, "billing.invoices.view")
+	if err != nil {
+		t.Error("unexpected error while checking federated permission.", err)
+	}
+	if !ok {
+		t.Error("expecting true to be returned for federated permission")
+	}
+
+	// cached result is reused until flushed
+	auth.RevokeRole(id, "role-a")
+	ok, _ = fed.CheckPermission(id, "billing.invoices.view")
+	if !ok {
+		t.Error("expecting the cached result to be returned")
+	}
+	client.Flush()
+	ok, _ = fed.CheckPermission(id, "billing.invoices.view")
+	if ok {
+		t.Error("expecting false after flushing the cache")
+	}
+
+	// missing permission on the owning service
+	_, err = fed.CheckPermission(id, "billing.invoices.missing")
+	if !errors.Is(err, authority.ErrPermissionNotFound) {
+		t.Error("expecting permission not found from the owning service")
+	}
+
+	// unreachable owning service
+	fed.Federate("shipping", authority.NewFederationClient(authority.FederationClientOptions{
+		URL:     "http://127.0.0.1:1",
+		Timeout: 100 * time.Millisecond,
+	}))
+	_, err = fed.CheckPermission(id, "shipping.orders.view")
+	if !errors.Is(err, authority.ErrFederationUnavailable) {
+		t.Error("expecting an error when the owning service is unreachable")
+	}
+
+	// the timeout applies to the custom http clients
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	fed.Federate("shipping", authority.NewFederationClient(authority.FederationClientOptions{
+		URL:        slow.URL,
+		Timeout:    50 * time.Millisecond,
+		HTTPClient: &http.Client{},
+	}))
+	start := time.Now()
+	_, err = fed.CheckPermission(id, "shipping.orders.view")
+	if !errors.Is(err, authority.ErrFederationUnavailable) || time.Since(start) > 500*time.Millisecond {
+		t.Error("expecting the check to time out with a custom http client.", err)
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name = ?", "billing.invoices.view").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestFederationAuthorizer(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	// the callers are rejected without an authorizer
+	server := authority.NewFederationServer(auth, nil, "billing")
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	auth.CreatePermission("billing.invoices.view", "a description permission")
+	id := uuid.New()
+	_, err := authority.NewFederationClient(authority.FederationClientOptions{URL: srv.URL}).CheckPermission(id, "billing.invoices.view")
+	if !errors.Is(err, authority.ErrFederationUnavailable) {
+		t.Error("expecting the callers to be rejected without an authorizer.", err)
+	}
+
+	server.SetAuthorizer(func(r *http.Request, userID uuid.UUID, permName string) error {
+		if r.Header.Get("Authorization") != "Bearer peer-token" {
+			return authority.ErrForbidden
+		}
+		return nil
+	})
+
+	// the callers without the token are rejected
+	client := authority.NewFederationClient(authority.FederationClientOptions{URL: srv.URL})
+	_, err = client.CheckPermission(id, "billing.invoices.view")
+	if !errors.Is(err, authority.ErrFederationUnavailable) {
+		t.Error("expecting the unauthorized caller to be rejected.", err)
+	}
+
+	client = authority.NewFederationClient(authority.FederationClientOptions{
+		URL:        srv.URL,
+		HTTPClient: &http.Client{Transport: bearerTransport("peer-token")},
+	})
+	ok, err := client.CheckPermission(id, "billing.invoices.view")
+	if err != nil {
+		t.Error("unexpected error while checking with the authorized caller.", err)
+	}
+	if ok {
+		t.Error("expecting false to be returned for the unassigned permission")
+	}
+
+	// clean up
+	db.Where("name = ?", "billing.invoices.view").Delete(authority.Permission{})
+}
+
+// bearerTransport adds a bearer token to the requests
+type bearerTransport string
+
+func (t bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+string(t))
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestFederationClock(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+	srv := httptest.NewServer(authority.NewFederationServer(auth, authority.AllowAnyCaller, "billing"))
+	defer srv.Close()
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("billing.invoices.view", "a description permission")
+	auth.AssignPermissions("role-a", []string{"billing.invoices.view"})
+	id := uuid.New()
+	auth.AssignRole(id, "role-a")
+
+	// the client inherits the clock of the federating instance
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)}
+	fed := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		Clock:        clock,
+	})
+	fed.Federate("billing", authority.NewFederationClient(authority.FederationClientOptions{
+		URL:      srv.URL,
+		CacheTTL: time.Minute,
+	}))
+
+	ok, _ := fed.CheckPermission(id, "billing.invoices.view")
+	if !ok {
+		t.Error("expecting true to be returned for federated permission")
+	}
+	auth.RevokeRole(id, "role-a")
+
+	clock.Advance(59 * time.Second)
+	ok, _ = fed.CheckPermission(id, "billing.invoices.view")
+	if !ok {
+		t.Error("expecting the cached result to be returned before the ttl")
+	}
+	clock.Advance(time.Second)
+	ok, _ = fed.CheckPermission(id, "billing.invoices.view")
+	if ok {
+		t.Error("expecting the cached result to expire with the clock")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name = ?", "billing.invoices.view").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
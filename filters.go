@@ -0,0 +1,101 @@
+package authority
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RoleFilter filters the listed roles, the zero value fields are ignored
+// and the set fields are combined
+type RoleFilter struct {
+	NameContains        string
+	DescriptionContains string
+	CreatedAfter        time.Time
+	// HasPermission keeps the roles having the permission assigned
+	HasPermission string
+	// AssignedToUser keeps the roles assigned to the user
+	AssignedToUser uuid.UUID
+}
+
+// PermissionFilter filters the listed permissions, the zero value fields are ignored
+// and the set fields are combined
+type PermissionFilter struct {
+	NameContains        string
+	DescriptionContains string
+	CreatedAfter        time.Time
+	// AssignedToRole keeps the permissions assigned to the role
+	AssignedToRole string
+	// AssignedToUser keeps the permissions assigned to the roles of the user
+	AssignedToUser uuid.UUID
+}
+
+// FilterRoles returns the stored roles matching the filter
+func (a *Authority) FilterRoles(f RoleFilter) ([]Role, error) {
+	q := a.DB.Model(Role{})
+	q = filterText(q, f.NameContains, f.DescriptionContains, f.CreatedAfter)
+	if f.HasPermission != "" {
+		perms := a.DB.Model(Permission{}).Select("id").Where("name = ?", f.HasPermission)
+		q = q.Where("id IN (?)", a.DB.Model(RolePermission{}).Select("role_id").Where("permission_id IN (?)", perms))
+	}
+	if f.AssignedToUser != uuid.Nil {
+		q = q.Where("id IN (?)", a.userRoleIDs(f.AssignedToUser))
+	}
+
+	var roles []Role
+	res := q.Order("name").Find(&roles)
+	return roles, storeError(res.Error)
+}
+
+// FilterPermissions returns the stored permissions matching the filter
+func (a *Authority) FilterPermissions(f PermissionFilter) ([]Permission, error) {
+	q := a.DB.Model(Permission{})
+	q = filterText(q, f.NameContains, f.DescriptionContains, f.CreatedAfter)
+	if f.AssignedToRole != "" {
+		roles := a.DB.Model(Role{}).Select("id").Where("name = ?", f.AssignedToRole)
+		q = q.Where("id IN (?)", a.DB.Model(RolePermission{}).Select("permission_id").Where("role_id IN (?)", roles))
+	}
+	if f.AssignedToUser != uuid.Nil {
+		q = q.Where("id IN (?)", a.DB.Model(RolePermission{}).Select("permission_id").Where("role_id IN (?)", a.userRoleIDs(f.AssignedToUser)))
+	}
+
+	var perms []Permission
+	res := q.Order("name").Find(&perms)
+	return perms, storeError(res.Error)
+}
+
+// userRoleIDs returns a sub query selecting the ids of the global roles of the user
+func (a *Authority) userRoleIDs(userID uuid.UUID) *gorm.DB {
+	return a.DB.Model(UserRole{}).Select("role_id").Where("tenant_id = ?", "").Where("user_id = ?", userID)
+}
+
+// filterText applies the filters shared by the roles and permissions
+func filterText(q *gorm.DB, name string, description string, createdAfter time.Time) *gorm.DB {
+	if name != "" {
+		q = q.Where("name LIKE ? ESCAPE '!'", likeContains(name))
+	}
+	if description != "" {
+		q = q.Where("description LIKE ? ESCAPE '!'", likeContains(description))
+	}
+	if !createdAfter.IsZero() {
+		q = q.Where("created_at > ?", createdAfter)
+	}
+
+	return q
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// likePrefix returns a LIKE pattern matching the values starting with the prefix
+// the pattern must be used with ESCAPE '!'
+func likePrefix(prefix string) string {
+	return likeEscaper.Replace(prefix) + "%"
+}
+
+// likeContains returns a LIKE pattern matching the values containing the text
+// the pattern must be used with ESCAPE '!'
+func likeContains(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
@@ -0,0 +1,72 @@
+package authority_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestFilterRoles(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	before := time.Now().Add(-time.Minute)
+	auth.CreateRole("filter-role-a", "manages billing")
+	auth.CreateRole("filter-role-b", "manages 100% of shipping")
+	auth.CreatePermission("filter-permission-a", "a description permission")
+	auth.AssignPermissions("filter-role-a", []string{"filter-permission-a"})
+	id := uuid.New()
+	auth.AssignRole(id, "filter-role-b")
+
+	roles, err := auth.FilterRoles(authority.RoleFilter{NameContains: "filter-role"})
+	if err != nil {
+		t.Error("unexpected error while filtering roles.", err)
+	}
+	if len(roles) != 2 {
+		t.Error("expecting the roles matching the name to be returned")
+	}
+	roles, _ = auth.FilterRoles(authority.RoleFilter{NameContains: "filter-role", DescriptionContains: "100%"})
+	if len(roles) != 1 || roles[0].Name != "filter-role-b" {
+		t.Error("expecting the roles matching the description to be returned")
+	}
+	roles, _ = auth.FilterRoles(authority.RoleFilter{HasPermission: "filter-permission-a"})
+	if len(roles) != 1 || roles[0].Name != "filter-role-a" {
+		t.Error("expecting the roles having the permission to be returned")
+	}
+	roles, _ = auth.FilterRoles(authority.RoleFilter{AssignedToUser: id})
+	if len(roles) != 1 || roles[0].Name != "filter-role-b" {
+		t.Error("expecting the roles assigned to the user to be returned")
+	}
+	roles, _ = auth.FilterRoles(authority.RoleFilter{NameContains: "filter-role", CreatedAfter: before})
+	if len(roles) != 2 {
+		t.Error("expecting the roles created after the time to be returned")
+	}
+	roles, _ = auth.FilterRoles(authority.RoleFilter{NameContains: "filter-role", CreatedAfter: time.Now().Add(time.Minute)})
+	if len(roles) != 0 {
+		t.Error("expecting no roles created in the future")
+	}
+
+	perms, err := auth.FilterPermissions(authority.PermissionFilter{AssignedToRole: "filter-role-a"})
+	if err != nil {
+		t.Error("unexpected error while filtering permissions.", err)
+	}
+	if len(perms) != 1 || perms[0].Name != "filter-permission-a" {
+		t.Error("expecting the permissions of the role to be returned")
+	}
+	perms, _ = auth.FilterPermissions(authority.PermissionFilter{AssignedToUser: id})
+	if len(perms) != 0 {
+		t.Error("expecting no permissions for the user")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "filter-role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("user_id = ?", id).Delete(authority.UserRole{})
+	db.Where("name = ?", "filter-permission-a").Delete(authority.Permission{})
+	db.Where("name IN (?)", []string{"filter-role-a", "filter-role-b"}).Delete(authority.Role{})
+}
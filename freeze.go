@@ -0,0 +1,39 @@
+package authority
+
+import (
+	"sync/atomic"
+)
+
+// FreezePolicy rejects all the mutations with ErrPolicyFrozen until Unfreeze is called
+// the checks and reads are still answered, the freeze applies to this instance only
+func (a *Authority) FreezePolicy() {
+	atomic.StoreInt32(&a.frozen, 1)
+}
+
+// Unfreeze accepts the mutations again
+func (a *Authority) Unfreeze() {
+	atomic.StoreInt32(&a.frozen, 0)
+}
+
+// IsFrozen reports whether the mutations are rejected
+func (a *Authority) IsFrozen() bool {
+	return atomic.LoadInt32(&a.frozen) == 1
+}
+
+// IsReadOnly reports whether the instance was initiated read only
+// unlike a freeze it cannot be lifted
+func (a *Authority) IsReadOnly() bool {
+	return a.readOnly
+}
+
+// checkMutable returns an error if the mutations are rejected
+func (a *Authority) checkMutable() error {
+	if a.readOnly {
+		return ErrReadOnly
+	}
+	if a.IsFrozen() {
+		return ErrPolicyFrozen
+	}
+
+	return nil
+}
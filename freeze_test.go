@@ -0,0 +1,79 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestFreezePolicy(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	auth.FreezePolicy()
+	if !auth.IsFrozen() {
+		t.Error("expecting the policy to be frozen")
+	}
+	err := auth.CreateRole("role-b", "b description role")
+	if !errors.Is(err, authority.ErrPolicyFrozen) {
+		t.Error("expecting an error when creating a role while frozen")
+	}
+	if authority.ErrorCodeOf(err).HTTPStatus() != http.StatusLocked {
+		t.Error("expecting the locked status")
+	}
+	_, err = auth.RevokeRole(userID, "role-a")
+	if !errors.Is(err, authority.ErrPolicyFrozen) {
+		t.Error("expecting an error when revoking a role while frozen")
+	}
+	_, err = auth.ImportAssignmentsCSV(strings.NewReader(userID.String()+",role-a\n"), authority.ImportOptions{})
+	if !errors.Is(err, authority.ErrPolicyFrozen) {
+		t.Error("expecting an error when importing while frozen")
+	}
+	_, err = auth.ImportAssignmentsCSV(strings.NewReader(userID.String()+",role-a\n"), authority.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Error("expecting a dry run import to be allowed while frozen", err)
+	}
+	_, err = auth.RepairAssignments(userID)
+	if !errors.Is(err, authority.ErrPolicyFrozen) {
+		t.Error("expecting an error when repairing the assignments while frozen")
+	}
+	_, err = auth.RepairAllAssignments(context.Background())
+	if !errors.Is(err, authority.ErrPolicyFrozen) {
+		t.Error("expecting an error when starting a repair job while frozen")
+	}
+	_, err = auth.ResumeRepair(context.Background(), 1)
+	if !errors.Is(err, authority.ErrPolicyFrozen) {
+		t.Error("expecting an error when resuming a repair job while frozen")
+	}
+	err = auth.PartitionUserRoles(4)
+	if !errors.Is(err, authority.ErrPolicyFrozen) {
+		t.Error("expecting an error when partitioning while frozen")
+	}
+
+	// checks are still answered
+	ok, err := auth.CheckRole(userID, "role-a")
+	if err != nil || !ok {
+		t.Error("expecting the role check to be answered while frozen")
+	}
+
+	auth.Unfreeze()
+	_, err = auth.RevokeRole(userID, "role-a")
+	if err != nil {
+		t.Error("unexpected error while revoking role after unfreezing.", err)
+	}
+
+	// clean up
+	db.Where("user_id = ?", userID).Delete(authority.AssignmentEvent{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
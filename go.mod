@@ -5,6 +5,7 @@ go 1.17
 require (
 	github.com/google/uuid v1.3.0
 	github.com/joho/godotenv v1.4.0
+	github.com/lib/pq v1.10.9
 	gorm.io/driver/mysql v1.3.2
 	gorm.io/gorm v1.23.2
 )
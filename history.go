@@ -0,0 +1,235 @@
+package authority
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetUserRolesAt returns the roles assigned to the user at the given time
+// the roles are rebuilt from the assignment history, the assignments made before
+// the history was recorded are not known
+func (a *Authority) GetUserRolesAt(userID uuid.UUID, at time.Time) ([]string, error) {
+	return a.GetUserRolesAtContext(context.Background(), userID, at)
+}
+
+// GetUserRolesAtContext returns the roles assigned to the user at the given time
+// within the tenant of the context
+func (a *Authority) GetUserRolesAtContext(ctx context.Context, userID uuid.UUID, at time.Time) ([]string, error) {
+	roles, err := a.rolesAt(ctx, userID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for _, name := range roles {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// CheckPermissionAt checks if the user had the permission at the given time
+// the assignments are rebuilt from the assignment history, the implications are
+// the current ones
+// a permission that doesn't exist anymore is matched by the name it had when assigned
+func (a *Authority) CheckPermissionAt(userID uuid.UUID, permName string, at time.Time) (bool, error) {
+	return a.CheckPermissionAtContext(context.Background(), userID, permName, at)
+}
+
+// CheckPermissionAtContext checks if the user had the permission at the given time
+// within the tenant of the context
+func (a *Authority) CheckPermissionAtContext(ctx context.Context, userID uuid.UUID, permName string, at time.Time) (bool, error) {
+	roles, err := a.rolesAt(ctx, userID, at)
+	if err != nil || len(roles) == 0 {
+		return false, err
+	}
+	var roleIDs []uint
+	for id := range roles {
+		roleIDs = append(roleIDs, id)
+	}
+
+	var events []AssignmentEvent
+	res := a.DB.WithContext(ctx).Where("created_at <= ?", at).
+		Where("(role_id IN (?) AND action IN (?)) OR action = ?", roleIDs, []string{EventPermissionAssigned, EventPermissionRevoked}, EventPermissionDeleted).
+		Order("id").Find(&events)
+	if res.Error != nil {
+		return false, storeError(res.Error)
+	}
+
+	// the permissions granted by every role
+	granted := map[uint]map[uint]string{}
+	for _, e := range events {
+		switch e.Action {
+		case EventPermissionAssigned:
+			if granted[e.RoleID] == nil {
+				granted[e.RoleID] = map[uint]string{}
+			}
+			granted[e.RoleID][e.PermissionID] = e.PermissionName
+		case EventPermissionRevoked:
+			delete(granted[e.RoleID], e.PermissionID)
+		case EventPermissionDeleted:
+			for _, perms := range granted {
+				delete(perms, e.PermissionID)
+			}
+		}
+	}
+
+	// the permissions implying the checked one if it still exists
+	impliers := map[uint]bool{}
+	perm, err := a.findPermission(permName)
+	if err != nil && !errors.Is(err, ErrPermissionNotFound) {
+		return false, err
+	}
+	if err == nil {
+		ids, err := a.impliersOf(perm.ID)
+		if err != nil {
+			return false, err
+		}
+		for _, id := range ids {
+			impliers[id] = true
+		}
+	}
+
+	for _, perms := range granted {
+		for id, name := range perms {
+			if name == permName || impliers[id] {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// rolesAt returns the names of the roles assigned to the user at the given time by id
+func (a *Authority) rolesAt(ctx context.Context, userID uuid.UUID, at time.Time) (map[uint]string, error) {
+	var events []AssignmentEvent
+	res := a.DB.WithContext(ctx).Where("created_at <= ?", at).
+		Where("(user_id = ? AND tenant_id = ? AND action IN (?)) OR action = ?", userID, TenantFromContext(ctx), []string{EventRoleAssigned, EventRoleRevoked}, EventRoleDeleted).
+		Order("id").Find(&events)
+	if res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	roles := map[uint]string{}
+	for _, e := range events {
+		if e.Action == EventRoleAssigned {
+			roles[e.RoleID] = e.RoleName
+		} else {
+			delete(roles, e.RoleID)
+		}
+	}
+
+	return roles, nil
+}
+
+// recordEvents appends the events to the assignment history
+// and checks them against the anomaly rules
+func (a *Authority) recordEvents(db *gorm.DB, events ...AssignmentEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// the replayed events keep the time they were recorded at
+	if at, ok := eventTimeFromContext(db.Statement.Context); ok {
+		for i := range events {
+			events[i].CreatedAt = at
+		}
+	}
+	if res := db.Create(&events); res.Error != nil {
+		return storeError(res.Error)
+	}
+	// the anomalies of the events recorded in a transaction are detected once it's committed
+	if p, ok := db.Get(eventsSetting); ok {
+		pending := p.(*pendingEvents)
+		pending.events = append(pending.events, events...)
+		return nil
+	}
+	a.detectAnomalies(events)
+
+	return nil
+}
+
+// eventsSetting is the gorm setting collecting the events recorded in a transaction
+const eventsSetting = "authority:events"
+
+// pendingEvents are the events recorded in a transaction not committed yet
+type pendingEvents struct {
+	events []AssignmentEvent
+}
+
+// transaction runs the func in a transaction of the db, the anomalies of the events
+// recorded in it are detected after the commit so a rolled back change is never reported
+func (a *Authority) transaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	if _, ok := db.Get(eventsSetting); ok {
+		return db.Transaction(fc)
+	}
+
+	pending := &pendingEvents{}
+	if err := db.Set(eventsSetting, pending).Transaction(fc); err != nil {
+		return err
+	}
+	a.detectAnomalies(pending.events)
+
+	return nil
+}
+
+// userRoleEvent returns an event of the role of a user
+func userRoleEvent(action string, userID uuid.UUID, tenant string, role Role) AssignmentEvent {
+	return AssignmentEvent{Action: action, UserID: userID, TenantID: tenant, RoleID: role.ID, RoleName: role.Name}
+}
+
+// permissionDeletedEvents returns the events of the deleted permissions
+func permissionDeletedEvents(perms []Permission) []AssignmentEvent {
+	var events []AssignmentEvent
+	for _, p := range perms {
+		events = append(events, AssignmentEvent{Action: EventPermissionDeleted, PermissionID: p.ID, PermissionName: p.Name})
+	}
+
+	return events
+}
+
+// rolesByID returns the roles of the given ids so the events carry their names
+func rolesByID(db *gorm.DB, ids []uint) (map[uint]Role, error) {
+	roles := map[uint]Role{}
+	if len(ids) == 0 {
+		return roles, nil
+	}
+	var found []Role
+	if res := db.Where("id IN (?)", ids).Find(&found); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+	for _, r := range found {
+		roles[r.ID] = r
+	}
+
+	return roles, nil
+}
+
+// permissionsByID returns the permissions of the given ids so the events carry their names
+func permissionsByID(db *gorm.DB, ids []uint) (map[uint]Permission, error) {
+	perms := map[uint]Permission{}
+	if len(ids) == 0 {
+		return perms, nil
+	}
+	var found []Permission
+	if res := db.Where("id IN (?)", ids).Find(&found); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+	for _, p := range found {
+		perms[p.ID] = p
+	}
+
+	return perms, nil
+}
+
+// rolePermissionEvent returns an event of the permission of a role
+func rolePermissionEvent(action string, role Role, perm Permission) AssignmentEvent {
+	return AssignmentEvent{Action: action, RoleID: role.ID, RoleName: role.Name, PermissionID: perm.ID, PermissionName: perm.Name}
+}
@@ -0,0 +1,125 @@
+package authority_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestAssignmentHistory(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	auth.AssignRole(userID, "role-a")
+	time.Sleep(10 * time.Millisecond)
+	assigned := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	auth.RevokeRole(userID, "role-a")
+
+	roles, err := auth.GetUserRolesAt(userID, assigned)
+	if err != nil {
+		t.Error("unexpected error while getting user roles at a time.", err)
+	}
+	if len(roles) != 1 || roles[0] != "role-a" {
+		t.Error("expecting the role to be assigned at that time")
+	}
+	roles, _ = auth.GetUserRolesAt(userID, before)
+	if len(roles) != 0 {
+		t.Error("expecting no roles before the assignment")
+	}
+
+	ok, err := auth.CheckPermissionAt(userID, "permission-a", assigned)
+	if err != nil {
+		t.Error("unexpected error while checking permission at a time.", err)
+	}
+	if !ok {
+		t.Error("expecting the permission to be granted at that time")
+	}
+	ok, _ = auth.CheckPermissionAt(userID, "permission-a", time.Now())
+	if ok {
+		t.Error("expecting the permission to be denied after the revoke")
+	}
+
+	// deleted roles are still reported
+	time.Sleep(10 * time.Millisecond)
+	auth.DeleteRole("role-a")
+	auth.DeletePermission("permission-a")
+	ok, _ = auth.CheckPermissionAt(userID, "permission-a", assigned)
+	if !ok {
+		t.Error("expecting the deleted permission to be granted at that time")
+	}
+	var deletes int64
+	db.Model(&authority.AssignmentEvent{}).Where("action = ?", authority.EventPermissionDeleted).Where("permission_name = ?", "permission-a").Count(&deletes)
+	if deletes == 0 {
+		t.Error("expecting the delete of the permission to be recorded")
+	}
+
+	// clean up
+	db.Where("user_id = ?", userID).Delete(authority.AssignmentEvent{})
+	db.Where("role_name = ?", "role-a").Delete(authority.AssignmentEvent{})
+	db.Where("permission_name = ?", "permission-a").Delete(authority.AssignmentEvent{})
+}
+
+func TestAssignmentHistoryFailure(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	userID := uuid.New()
+
+	// the inserts of the events fail
+	errInsert := errors.New("insert failed")
+	db.Callback().Create().Before("gorm:create").Register("test:fail_events", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Model.(*[]authority.AssignmentEvent); ok {
+			tx.AddError(errInsert)
+		}
+	})
+
+	err := auth.AssignRole(userID, "role-a")
+	if !errors.Is(err, errInsert) {
+		t.Error("expecting the history error to be returned.", err)
+	}
+	ok, _ := auth.CheckRole(userID, "role-a")
+	if ok {
+		t.Error("expecting the role not to be assigned without its event")
+	}
+	err = auth.AssignPermissions("role-a", []string{"permission-a"})
+	if !errors.Is(err, errInsert) {
+		t.Error("expecting the history error to be returned.", err)
+	}
+	ok, _ = auth.CheckRolePermission("role-a", "permission-a")
+	if ok {
+		t.Error("expecting the permission not to be assigned without its event")
+	}
+	_, err = auth.DeletePermission("permission-a")
+	if !errors.Is(err, errInsert) {
+		t.Error("expecting the history error to be returned.", err)
+	}
+	var count int64
+	db.Model(&authority.Permission{}).Where("name = ?", "permission-a").Count(&count)
+	if count != 1 {
+		t.Error("expecting the permission not to be deleted without its event")
+	}
+	db.Callback().Create().Remove("test:fail_events")
+
+	// clean up
+	auth.DeletePermission("permission-a")
+	auth.DeleteRole("role-a")
+	db.Where("permission_name = ?", "permission-a").Delete(authority.AssignmentEvent{})
+	db.Where("role_name = ?", "role-a").Delete(authority.AssignmentEvent{})
+}
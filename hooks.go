@@ -0,0 +1,87 @@
+package authority
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// the operations of the mutations
+const (
+	OpCreateRole             = "create_role"
+	OpCreatePermission       = "create_permission"
+	OpAssignPermissions      = "assign_permissions"
+	OpSyncPermissions        = "sync_permissions"
+	OpAssignRole             = "assign_role"
+	OpRevokeRole             = "revoke_role"
+	OpRevokePermission       = "revoke_permission"
+	OpRevokeRolePermission   = "revoke_role_permission"
+	OpDeleteRole             = "delete_role"
+	OpDeletePermission       = "delete_permission"
+	OpUpdateRole             = "update_role"
+	OpUpdatePermission       = "update_permission"
+	OpSetRoleOwner           = "set_role_owner"
+	OpSetRoleManager         = "set_role_manager"
+	OpInstallMetaPermissions = "install_meta_permissions"
+	OpAddImplication         = "add_implication"
+	OpRemoveImplication      = "remove_implication"
+	OpRegisterModule         = "register_module"
+	OpRemoveModule           = "remove_module"
+	OpRegisterNamespace      = "register_namespace"
+	OpAssignNamespace        = "assign_namespace"
+	OpRevokeNamespace        = "revoke_namespace"
+	OpDeleteNamespace        = "delete_namespace"
+	OpImportAssignments      = "import_assignments"
+	OpRenamePrefix           = "rename_prefix"
+	OpRepairAssignments      = "repair_assignments"
+	OpPartitionUserRoles     = "partition_user_roles"
+)
+
+// Mutation describes a change about to be made to the policy
+type Mutation struct {
+	Operation string
+	// Tenant and Actor are taken from the context of the mutation
+	Tenant      string
+	Actor       uuid.UUID
+	UserID      uuid.UUID
+	Role        string
+	Permissions []string
+	// Name is the name of the module or namespace of their operations, or the new tables prefix
+	Name string
+	// Note is the justification of the assignments
+	Note AssignmentNote
+}
+
+// MutationHook is called before every mutation, returning an error vetoes the mutation
+type MutationHook func(ctx context.Context, m Mutation) error
+
+// SetMutationHook sets the hook called before every mutation, it's the place
+// to enforce quotas like the max roles per tenant or the max grants per minute per admin
+// the vetoed mutations return ErrMutationRejected wrapping the error of the hook
+func (a *Authority) SetMutationHook(hook MutationHook) {
+	a.hookMu.Lock()
+	defer a.hookMu.Unlock()
+	a.mutationHook = hook
+}
+
+// checkMutation returns an error if the policy is frozen or the hook vetoes the mutation
+func (a *Authority) checkMutation(ctx context.Context, m Mutation) error {
+	if err := a.checkMutable(); err != nil {
+		return err
+	}
+
+	a.hookMu.RLock()
+	hook := a.mutationHook
+	a.hookMu.RUnlock()
+	if hook == nil {
+		return nil
+	}
+
+	m.Tenant = TenantFromContext(ctx)
+	m.Actor = ActorFromContext(ctx)
+	if err := hook(ctx, m); err != nil {
+		return wrapError(ErrMutationRejected, err)
+	}
+
+	return nil
+}
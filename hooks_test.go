@@ -0,0 +1,170 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestMutationHook(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+
+	// allow a single grant per admin
+	errQuota := errors.New("quota exceeded")
+	grants := map[uuid.UUID]int{}
+	var last authority.Mutation
+	auth.SetMutationHook(func(ctx context.Context, m authority.Mutation) error {
+		last = m
+		if m.Operation != authority.OpAssignRole {
+			return nil
+		}
+		if grants[m.Actor] >= 1 {
+			return errQuota
+		}
+		grants[m.Actor]++
+		return nil
+	})
+
+	adminID := uuid.New()
+	ctx := authority.WithActor(authority.WithTenant(context.Background(), "tenant-a"), adminID)
+	userA, userB := uuid.New(), uuid.New()
+	err := auth.AssignRoleContext(ctx, userA, "role-a")
+	if err != nil {
+		t.Error("unexpected error while assigning role.", err)
+	}
+	if last.Actor != adminID || last.Tenant != "tenant-a" || last.UserID != userA || last.Role != "role-a" {
+		t.Errorf("unexpected mutation %+v", last)
+	}
+
+	err = auth.AssignRoleContext(ctx, userB, "role-a")
+	if !errors.Is(err, authority.ErrMutationRejected) || !errors.Is(err, errQuota) {
+		t.Error("expecting the second grant to be vetoed", err)
+	}
+	ok, _ := auth.CheckRoleContext(ctx, userB, "role-a")
+	if ok {
+		t.Error("expecting the vetoed grant not to be applied")
+	}
+
+	auth.SetMutationHook(nil)
+	_, err = auth.RevokeRoleContext(ctx, userA, "role-a")
+	if err != nil {
+		t.Error("unexpected error while revoking role without a hook.", err)
+	}
+
+	// clean up
+	db.Where("user_id = ?", userA).Delete(authority.AssignmentEvent{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestMutationHookActor(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	var mutations []authority.Mutation
+	auth.SetMutationHook(func(ctx context.Context, m authority.Mutation) error {
+		mutations = append(mutations, m)
+		return nil
+	})
+	defer auth.SetMutationHook(nil)
+
+	// the context variants pass the actor to the hook
+	adminID := uuid.New()
+	ctx := authority.WithActor(context.Background(), adminID)
+	steps := []error{
+		auth.CreateRoleContext(ctx, "actor-role", "a description role"),
+		auth.CreatePermissionContext(ctx, "actor-permission", "a description permission"),
+		auth.CreatePermissionContext(ctx, "actor-implied", "a description permission"),
+		auth.AssignPermissionsContext(ctx, "actor-role", []string{"actor-permission"}),
+		auth.AddPermissionImplicationContext(ctx, "actor-permission", "actor-implied"),
+		auth.RemovePermissionImplicationContext(ctx, "actor-permission", "actor-implied"),
+		auth.UpdateRoleByNameContext(ctx, "actor-role", "actor-role", "b description role"),
+		auth.RegisterNamespaceContext(ctx, "actor", "a description namespace"),
+		auth.DeleteNamespaceContext(ctx, "actor"),
+	}
+	_, err := auth.RevokeRolePermissionContext(ctx, "actor-role", "actor-permission")
+	steps = append(steps, err)
+	_, err = auth.DeleteRoleContext(ctx, "actor-role")
+	steps = append(steps, err)
+	_, err = auth.DeletePermissionContext(ctx, "actor-permission")
+	steps = append(steps, err)
+	for i, err := range steps {
+		if err != nil {
+			t.Errorf("unexpected error at step %d. %v", i, err)
+		}
+	}
+	if len(mutations) != len(steps) {
+		t.Errorf("expecting a mutation per step, got %d", len(mutations))
+	}
+	for _, m := range mutations {
+		if m.Actor != adminID {
+			t.Errorf("expecting the actor of the context for %s, got %v", m.Operation, m.Actor)
+		}
+	}
+
+	// clean up
+	db.Where("role_name = ?", "actor-role").Delete(authority.AssignmentEvent{})
+	db.Where("permission_name = ?", "actor-permission").Delete(authority.AssignmentEvent{})
+	db.Where("name IN (?)", []string{"actor-permission", "actor-implied"}).Delete(authority.Permission{})
+}
+
+func TestMutationHookImport(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreateRole("role-b", "b description role")
+
+	// role-b is only granted through the approval flow
+	errApproval := errors.New("approval required")
+	var ops []string
+	auth.SetMutationHook(func(ctx context.Context, m authority.Mutation) error {
+		ops = append(ops, m.Operation)
+		if m.Operation == authority.OpAssignRole && m.Role == "role-b" {
+			return errApproval
+		}
+		return nil
+	})
+	defer auth.SetMutationHook(nil)
+
+	userA, userB := uuid.New(), uuid.New()
+	csv := fmt.Sprintf("%s,role-a\n%s,role-b\n%s,role-a\n", userA, userA, userB)
+	report, err := auth.ImportAssignmentsCSV(strings.NewReader(csv), authority.ImportOptions{})
+	if err != nil {
+		t.Error("unexpected error while importing assignments.", err)
+	}
+	if report.Assigned != 2 || len(report.Errors) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Errors[0].Line != 2 || !errors.Is(report.Errors[0].Err, errApproval) {
+		t.Errorf("expecting the vetoed row to be reported, got %+v", report.Errors[0])
+	}
+	if strings.Join(ops, ",") != "import_assignments,assign_role,assign_role,assign_role" {
+		t.Errorf("expecting the hook to be called for the import and every row, got %v", ops)
+	}
+	ok, _ := auth.CheckRole(userA, "role-b")
+	if ok {
+		t.Error("expecting the vetoed row not to be applied")
+	}
+	ok, _ = auth.CheckRole(userB, "role-a")
+	if !ok {
+		t.Error("expecting the allowed rows to be applied")
+	}
+
+	// clean up
+	db.Where("user_id IN (?)", []uuid.UUID{userA, userB}).Delete(authority.UserRole{})
+	db.Where("name IN (?)", []string{"role-a", "role-b"}).Delete(authority.Role{})
+}
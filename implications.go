@@ -0,0 +1,210 @@
+package authority
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// AddPermissionImplication declares that having the first permission implies having the second one
+// for example "posts.edit" implies "posts.view"
+// it returns an error if any of the permissions is not present in the database
+// it returns an error if the implication would create a cycle
+func (a *Authority) AddPermissionImplication(permName string, impliedPermName string) error {
+	return a.AddPermissionImplicationContext(context.Background(), permName, impliedPermName)
+}
+
+// AddPermissionImplicationContext declares an implication on behalf of the actor of the context
+func (a *Authority) AddPermissionImplicationContext(ctx context.Context, permName string, impliedPermName string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpAddImplication, Permissions: []string{permName, impliedPermName}}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+
+	perm, err := a.findPermission(permName)
+	if err != nil {
+		return err
+	}
+	implied, err := a.findPermission(impliedPermName)
+	if err != nil {
+		return err
+	}
+
+	// refuse cycles, the implied permission must not imply the permission already
+	impliers, err := a.impliersOf(perm.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range impliers {
+		if id == implied.ID {
+			return ErrImplicationCycle
+		}
+	}
+
+	var c int64
+	res := db.Model(PermissionImplication{}).Where("permission_id = ?", perm.ID).Where("implied_permission_id = ?", implied.ID).Count(&c)
+	if res.Error != nil {
+		return storeError(res.Error)
+	}
+	if c > 0 {
+		return nil
+	}
+
+	res = db.Create(&PermissionImplication{PermissionID: perm.ID, ImpliedPermissionID: implied.ID})
+	if res.Error != nil {
+		return storeError(res.Error)
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// RemovePermissionImplication removes a declared implication between two permissions
+// it returns an error if any of the permissions is not present in the database
+func (a *Authority) RemovePermissionImplication(permName string, impliedPermName string) error {
+	return a.RemovePermissionImplicationContext(context.Background(), permName, impliedPermName)
+}
+
+// RemovePermissionImplicationContext removes an implication on behalf of the actor of the context
+func (a *Authority) RemovePermissionImplicationContext(ctx context.Context, permName string, impliedPermName string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRemoveImplication, Permissions: []string{permName, impliedPermName}}); err != nil {
+		return err
+	}
+
+	perm, err := a.findPermission(permName)
+	if err != nil {
+		return err
+	}
+	implied, err := a.findPermission(impliedPermName)
+	if err != nil {
+		return err
+	}
+
+	res := a.DB.WithContext(ctx).Where("permission_id = ?", perm.ID).Where("implied_permission_id = ?", implied.ID).Delete(PermissionImplication{})
+	if res.Error != nil {
+		return storeError(res.Error)
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// GetImpliedPermissions returns all the permissions implied by the given one, directly or not
+// it returns an error if the permission is not present in the database
+func (a *Authority) GetImpliedPermissions(permName string) ([]string, error) {
+	perm, err := a.findPermission(permName)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := a.reachable(perm.ID, "permission_id", "implied_permission_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var perms []Permission
+	res := a.DB.Where("id IN (?)", ids).Find(&perms)
+	if res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	var result []string
+	for _, p := range perms {
+		result = append(result, p.Name)
+	}
+
+	return result, nil
+}
+
+// GetPermissionGraph returns the declared implications
+// the keys are the permission names and the values the names of the permissions they directly imply
+func (a *Authority) GetPermissionGraph() (map[string][]string, error) {
+	edges, err := a.loadImplications()
+	if err != nil {
+		return nil, err
+	}
+
+	var perms []Permission
+	res := a.DB.Find(&perms)
+	if res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+	names := map[uint]string{}
+	for _, p := range perms {
+		names[p.ID] = p.Name
+	}
+
+	graph := map[string][]string{}
+	for _, e := range edges {
+		graph[names[e.PermissionID]] = append(graph[names[e.PermissionID]], names[e.ImpliedPermissionID])
+	}
+
+	return graph, nil
+}
+
+// impliersOf returns the given permission id along with the ids of
+// all the permissions implying it, directly or not
+func (a *Authority) impliersOf(permID uint) ([]uint, error) {
+	ids, err := a.reachable(permID, "implied_permission_id", "permission_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]uint{permID}, ids...), nil
+}
+
+// reachable returns the ids reachable from the given id through the implications, excluding it
+// the edges are followed from a column to the other one level by level so only
+// the reachable edges are loaded
+func (a *Authority) reachable(permID uint, from string, to string) ([]uint, error) {
+	var result []uint
+	visited := map[uint]bool{permID: true}
+	level := []uint{permID}
+	for len(level) > 0 {
+		var next []uint
+		res := a.DB.Model(&PermissionImplication{}).Where(from+" IN (?)", level).Pluck(to, &next)
+		if res.Error != nil {
+			return nil, storeError(res.Error)
+		}
+
+		level = nil
+		for _, id := range next {
+			if !visited[id] {
+				visited[id] = true
+				result = append(result, id)
+				level = append(level, id)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (a *Authority) loadImplications() ([]PermissionImplication, error) {
+	var edges []PermissionImplication
+	res := a.DB.Find(&edges)
+	return edges, storeError(res.Error)
+}
+
+// walk returns the ids reachable from the given id, excluding it
+func walk(from uint, edges map[uint][]uint) []uint {
+	var result []uint
+	visited := map[uint]bool{from: true}
+	queue := []uint{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[id] {
+			if !visited[next] {
+				visited[next] = true
+				result = append(result, next)
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,96 @@
+package authority_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestPermissionImplications(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("posts.delete", "a description permission")
+	auth.CreatePermission("posts.edit", "a description permission")
+	auth.CreatePermission("posts.view", "a description permission")
+	auth.AssignPermissions("role-a", []string{"posts.delete"})
+	id := uuid.New()
+	auth.AssignRole(id, "role-a")
+
+	err := auth.AddPermissionImplication("posts.edit", "posts.view")
+	if err != nil {
+		t.Error("unexpected error while adding implication.", err)
+	}
+	err = auth.AddPermissionImplication("posts.delete", "posts.edit")
+	if err != nil {
+		t.Error("unexpected error while adding implication.", err)
+	}
+
+	// implications are expanded at check time
+	ok, err := auth.CheckPermission(id, "posts.view")
+	if err != nil {
+		t.Error("unexpected error while checking implied permission.", err)
+	}
+	if !ok {
+		t.Error("expecting the implied permission to be granted")
+	}
+	ok, _ = auth.CheckRolePermission("role-a", "posts.edit")
+	if !ok {
+		t.Error("expecting the implied permission to be granted to the role")
+	}
+
+	// cycles are refused
+	err = auth.AddPermissionImplication("posts.view", "posts.delete")
+	if !errors.Is(err, authority.ErrImplicationCycle) {
+		t.Error("expecting an error when adding a cyclic implication")
+	}
+
+	// query the graph
+	implied, _ := auth.GetImpliedPermissions("posts.delete")
+	if len(implied) != 2 || !sliceHasString(implied, "posts.edit") || !sliceHasString(implied, "posts.view") {
+		t.Error("expecting the implied permissions to be returned")
+	}
+	graph, _ := auth.GetPermissionGraph()
+	if len(graph["posts.edit"]) != 1 || graph["posts.edit"][0] != "posts.view" {
+		t.Error("unexpected permission graph")
+	}
+
+	// the checks only load the edges reachable from the permission
+	auth.CreatePermission("other.edit", "a description permission")
+	auth.CreatePermission("other.view", "a description permission")
+	auth.AddPermissionImplication("other.edit", "other.view")
+	var loaded int64
+	db.Callback().Query().After("gorm:query").Register("test:implications", func(tx *gorm.DB) {
+		if strings.HasSuffix(tx.Statement.Table, "permission_implications") {
+			loaded += tx.RowsAffected
+		}
+	})
+	ok, _ = auth.CheckPermission(id, "posts.view")
+	db.Callback().Query().Remove("test:implications")
+	if !ok || loaded != 2 {
+		t.Errorf("expecting the two implications of posts.view to be loaded, got %d", loaded)
+	}
+
+	// removing an implication
+	auth.RemovePermissionImplication("posts.edit", "posts.view")
+	ok, _ = auth.CheckPermission(id, "posts.view")
+	if ok {
+		t.Error("expecting false after removing the implication")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("1 = 1").Delete(authority.PermissionImplication{})
+	db.Where("name LIKE ?", "posts.%").Or("name LIKE ?", "other.%").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
@@ -0,0 +1,240 @@
+package authority
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errImportColumns = errors.New("expecting two columns, the user id and the role name")
+	errImportUserID  = errors.New("invalid user id")
+)
+
+// ImportOptions has the options of an assignments import
+type ImportOptions struct {
+	// DryRun validates the rows and reports what would be applied without writing
+	DryRun bool
+	// BatchSize is the number of rows applied per transaction, defaults to 500
+	BatchSize int
+	// Header skips the first row
+	Header bool
+}
+
+// ImportRowError describes a rejected row
+type ImportRowError struct {
+	Line     int
+	UserID   string
+	RoleName string
+	Err      error
+}
+
+// ImportReport summarizes an assignments import
+type ImportReport struct {
+	// Rows is the number of rows read
+	Rows int
+	// Assigned is the number of roles assigned, or to be assigned in a dry run
+	Assigned int
+	// Skipped is the number of rows already assigned or duplicated in the file
+	Skipped int
+	// Errors are the rejected rows
+	Errors []ImportRowError
+}
+
+// importRow is a validated row waiting to be applied
+type importRow struct {
+	line     int
+	userID   uuid.UUID
+	roleName string
+}
+
+// ImportAssignmentsCSV assigns roles to users from a csv of userID,roleName rows
+// the rows are validated and applied in batched transactions, the rejected rows
+// are reported and don't stop the import
+// the mutation hook is called for the whole import then for every row as an OpAssignRole,
+// the rows vetoed by the hook are reported as rejected
+// it returns an error if the csv could not be read or a batch could not be applied
+func (a *Authority) ImportAssignmentsCSV(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	return a.importCSV(context.Background(), r, opts, 0, nil)
+}
+
+// importCSV imports the csv rows after skipping the given number of rows
+// the checkpoint func is called with the number of rows read after every applied batch
+// the context is checked before every batch
+func (a *Authority) importCSV(ctx context.Context, r io.Reader, opts ImportOptions, skip int, checkpoint func(rows int, report *ImportReport) error) (*ImportReport, error) {
+	if !opts.DryRun {
+		if err := a.checkMutation(ctx, Mutation{Operation: OpImportAssignments}); err != nil {
+			return &ImportReport{}, err
+		}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	report := &ImportReport{}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	seen := map[importRow]bool{}
+
+	var batch []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		line, _ := reader.FieldPos(0)
+		if opts.Header && line == 1 {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		report.Rows++
+
+		if len(record) != 2 {
+			report.Errors = append(report.Errors, ImportRowError{Line: line, Err: errImportColumns})
+			continue
+		}
+		userIDStr, roleName := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			report.Errors = append(report.Errors, ImportRowError{Line: line, UserID: userIDStr, RoleName: roleName, Err: errImportUserID})
+			continue
+		}
+		row := importRow{userID: userID, roleName: roleName}
+		if seen[row] {
+			report.Skipped++
+			continue
+		}
+		seen[row] = true
+		if !opts.DryRun {
+			// every assignment goes through the hook so the quotas apply to the imports
+			err := a.checkMutation(ctx, Mutation{Operation: OpAssignRole, UserID: userID, Role: roleName})
+			if errors.Is(err, ErrMutationRejected) {
+				report.Errors = append(report.Errors, ImportRowError{Line: line, UserID: userIDStr, RoleName: roleName, Err: err})
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+		}
+
+		row.line = line
+		batch = append(batch, row)
+		if len(batch) == opts.BatchSize {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			if err := a.importBatch(batch, TenantFromContext(ctx), opts.DryRun, report); err != nil {
+				return report, err
+			}
+			batch = nil
+			if checkpoint != nil {
+				if err := checkpoint(report.Rows, report); err != nil {
+					return report, err
+				}
+			}
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := a.importBatch(batch, TenantFromContext(ctx), opts.DryRun, report); err != nil {
+			return report, err
+		}
+	}
+	if checkpoint != nil {
+		if err := checkpoint(report.Rows, report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// importBatch validates the rows against the database and applies them within the tenant
+// in a transaction, the report is only updated once the transaction is committed
+func (a *Authority) importBatch(rows []importRow, tenantID string, dryRun bool, report *ImportReport) error {
+	var batch ImportReport
+	err := a.transaction(a.DB, func(tx *gorm.DB) error {
+		// find the roles at once
+		var roleNames []string
+		var userIDs []uuid.UUID
+		for _, row := range rows {
+			roleNames = append(roleNames, row.roleName)
+			userIDs = append(userIDs, row.userID)
+		}
+		var roles []Role
+		if res := tx.Where("name IN (?)", roleNames).Find(&roles); res.Error != nil {
+			return storeError(res.Error)
+		}
+		roleIDs := map[string]uint{}
+		for _, r := range roles {
+			roleIDs[r.Name] = r.ID
+		}
+
+		// find the existing assignments at once
+		var existing []UserRole
+		if res := tx.Where("tenant_id = ?", tenantID).Where("user_id IN (?)", userIDs).Find(&existing); res.Error != nil {
+			return storeError(res.Error)
+		}
+		type assignment struct {
+			userID uuid.UUID
+			roleID uint
+		}
+		assigned := map[assignment]bool{}
+		for _, ur := range existing {
+			assigned[assignment{ur.UserID, ur.RoleID}] = true
+		}
+
+		var userRoles []UserRole
+		var events []AssignmentEvent
+		for _, row := range rows {
+			roleID, found := roleIDs[row.roleName]
+			if !found {
+				batch.Errors = append(batch.Errors, ImportRowError{Line: row.line, UserID: row.userID.String(), RoleName: row.roleName, Err: ErrRoleNotFound})
+				continue
+			}
+			if assigned[assignment{row.userID, roleID}] {
+				batch.Skipped++
+				continue
+			}
+			userRoles = append(userRoles, UserRole{UserID: row.userID, RoleID: roleID, TenantID: tenantID})
+			events = append(events, userRoleEvent(EventRoleAssigned, row.userID, tenantID, Role{ID: roleID, Name: row.roleName}))
+		}
+
+		if dryRun || len(userRoles) == 0 {
+			batch.Assigned = len(userRoles)
+			return nil
+		}
+
+		if res := tx.Create(&userRoles); res.Error != nil {
+			return storeError(res.Error)
+		}
+		batch.Assigned = len(userRoles)
+		return a.recordEvents(tx, events...)
+	})
+	if err != nil {
+		return err
+	}
+	report.Assigned += batch.Assigned
+	report.Skipped += batch.Skipped
+	report.Errors = append(report.Errors, batch.Errors...)
+	if !dryRun {
+		a.invalidate(uuid.Nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package authority_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestImportAssignmentsCSV(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreateRole("role-b", "a description role")
+	id := uuid.New()
+	id2 := uuid.New()
+	auth.AssignRole(id2, "role-b")
+
+	csv := fmt.Sprintf("user_id,role\n%s,role-a\n%s,role-b\n%s,role-a\n%s,role-b\nnot-a-uuid,role-a\n%s,role-aa\n%s\n",
+		id, id, id, id2, id, id)
+
+	// dry run
+	report, err := auth.ImportAssignmentsCSV(strings.NewReader(csv), authority.ImportOptions{DryRun: true, Header: true, BatchSize: 2})
+	if err != nil {
+		t.Error("unexpected error while importing assignments.", err)
+	}
+	if report.Rows != 7 || report.Assigned != 2 || report.Skipped != 2 || len(report.Errors) != 3 {
+		t.Errorf("unexpected dry run report %+v", report)
+	}
+	ok, _ := auth.CheckRole(id, "role-a")
+	if ok {
+		t.Error("expecting nothing to be applied in a dry run")
+	}
+
+	// apply
+	report, err = auth.ImportAssignmentsCSV(strings.NewReader(csv), authority.ImportOptions{Header: true, BatchSize: 2})
+	if err != nil {
+		t.Error("unexpected error while importing assignments.", err)
+	}
+	if report.Assigned != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	ok, _ = auth.CheckRole(id, "role-a")
+	if !ok {
+		t.Error("expecting the imported role to be assigned")
+	}
+	ok, _ = auth.CheckRole(id, "role-b")
+	if !ok {
+		t.Error("expecting the imported role to be assigned")
+	}
+	if len(report.Errors) > 0 && report.Errors[0].Line != 6 {
+		t.Error("expecting the line of the rejected row to be reported")
+	}
+
+	// clean up
+	db.Where("user_id IN (?)", []uuid.UUID{id, id2}).Delete(authority.UserRole{})
+	db.Where("name IN (?)", []string{"role-a", "role-b"}).Delete(authority.Role{})
+}
+
+func TestImportAssignmentsCSVFailure(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	id := uuid.New()
+
+	// the inserts of the user roles fail
+	errInsert := errors.New("insert failed")
+	db.Callback().Create().Before("gorm:create").Register("test:fail_user_roles", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Model.(*[]authority.UserRole); ok {
+			tx.AddError(errInsert)
+		}
+	})
+	defer db.Callback().Create().Remove("test:fail_user_roles")
+
+	report, err := auth.ImportAssignmentsCSV(strings.NewReader(fmt.Sprintf("%s,role-a\n", id)), authority.ImportOptions{})
+	if !errors.Is(err, errInsert) {
+		t.Error("expecting the insert error to be returned.", err)
+	}
+	if report.Assigned != 0 {
+		t.Errorf("expecting nothing to be reported as assigned, got %+v", report)
+	}
+
+	// clean up
+	db.Where("user_id = ?", id).Delete(authority.UserRole{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
@@ -0,0 +1,42 @@
+package authority
+
+import (
+	"time"
+)
+
+// the statuses of a job
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// JobState represents the database model of the progress of a long running job
+// it's the checkpoint used to resume an interrupted job
+type JobState struct {
+	ID        uint
+	Kind      string
+	Status    string
+	Processed int64
+	Assigned  int64
+	Skipped   int64
+	Failed    int64
+	// Repaired is the number of inconsistent rows removed by the repair jobs
+	Repaired int64
+	Error    string
+	// Cursor is the last key processed by the jobs walking a table
+	Cursor    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName sets the table name
+func (j JobState) TableName() string {
+	return defaultPrefix() + j.table()
+}
+
+// table returns the table name without the prefix
+func (JobState) table() string {
+	return "jobs"
+}
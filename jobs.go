@@ -0,0 +1,196 @@
+package authority
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// the kinds of jobs
+const (
+	jobKindImport = "import_assignments"
+	jobKindRepair = "repair_assignments"
+)
+
+// JobProgress is a snapshot of the progress of a job
+// the counts include the runs made before the job was resumed
+type JobProgress struct {
+	Status    string
+	Processed int64
+	Assigned  int64
+	Skipped   int64
+	Failed    int64
+	Repaired  int64
+}
+
+// Job is a long running job processed in batches
+// the progress is saved after every batch so that an interrupted job can be resumed
+type Job struct {
+	id   uint
+	done chan struct{}
+
+	mu       sync.Mutex
+	progress JobProgress
+	report   *ImportReport
+	err      error
+}
+
+// ID returns the id of the job, it's used to resume the job
+func (j *Job) ID() uint {
+	return j.id
+}
+
+// Progress returns the current progress of the job
+func (j *Job) Progress() JobProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// Done returns a channel closed when the job stops
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the job to stop and returns the report of this run,
+// the report is nil for the jobs other than imports
+// it returns the context error if the job was canceled
+func (j *Job) Wait() (*ImportReport, error) {
+	<-j.done
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.report, j.err
+}
+
+// StartImportJob imports role assignments from a csv in the background
+// within the tenant of the context
+// the job stops between two batches when the context is canceled
+func (a *Authority) StartImportJob(ctx context.Context, r io.Reader, opts ImportOptions) (*Job, error) {
+	if !opts.DryRun {
+		if err := a.checkMutable(); err != nil {
+			return nil, err
+		}
+	}
+
+	state := JobState{Kind: jobKindImport, Status: JobRunning}
+	if res := a.DB.Create(&state); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	return a.runImportJob(ctx, state, r, opts), nil
+}
+
+// ResumeImportJob resumes an interrupted import job from its last checkpoint
+// the reader must provide the same csv as the interrupted run, the rows
+// processed by the previous runs are skipped
+// the assignments are made within the tenant of the context like the interrupted run
+// it returns an error if the job is not present in the database or already completed
+func (a *Authority) ResumeImportJob(ctx context.Context, jobID uint, r io.Reader, opts ImportOptions) (*Job, error) {
+	if !opts.DryRun {
+		if err := a.checkMutable(); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := a.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Kind != jobKindImport || state.Status == JobCompleted {
+		return nil, ErrJobNotResumable
+	}
+
+	state.Status = JobRunning
+	state.Error = ""
+	if res := a.DB.Save(&state); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	return a.runImportJob(ctx, state, r, opts), nil
+}
+
+// GetJob returns the saved progress of a job
+// it returns an error if the job is not present in the database
+func (a *Authority) GetJob(jobID uint) (JobState, error) {
+	var state JobState
+	res := a.DB.Where("id = ?", jobID).First(&state)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return state, ErrJobNotFound
+		}
+		return state, storeError(res.Error)
+	}
+
+	return state, nil
+}
+
+func (a *Authority) runImportJob(ctx context.Context, state JobState, r io.Reader, opts ImportOptions) *Job {
+	base := state
+	return a.runJob(ctx, state, func(ctx context.Context, state *JobState, save func() error) (*ImportReport, error) {
+		checkpoint := func(rows int, report *ImportReport) error {
+			state.Processed = base.Processed + int64(rows)
+			state.Assigned = base.Assigned + int64(report.Assigned)
+			state.Skipped = base.Skipped + int64(report.Skipped)
+			state.Failed = base.Failed + int64(len(report.Errors))
+			return save()
+		}
+
+		return a.importCSV(ctx, r, opts, int(base.Processed), checkpoint)
+	})
+}
+
+// runJob runs the job in the background
+// the run func updates the state and saves it after every batch
+func (a *Authority) runJob(ctx context.Context, state JobState, run func(ctx context.Context, state *JobState, save func() error) (*ImportReport, error)) *Job {
+	j := &Job{id: state.ID, done: make(chan struct{})}
+	j.progress = progressOf(state)
+
+	go func() {
+		defer close(j.done)
+
+		save := func() error {
+			if res := a.DB.Save(&state); res.Error != nil {
+				return storeError(res.Error)
+			}
+
+			j.mu.Lock()
+			j.progress = progressOf(state)
+			j.mu.Unlock()
+			return nil
+		}
+
+		report, err := run(ctx, &state, save)
+
+		state.Status = JobCompleted
+		if err != nil {
+			state.Status = JobFailed
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				state.Status = JobCanceled
+			}
+			state.Error = err.Error()
+		}
+		a.DB.Save(&state)
+
+		j.mu.Lock()
+		j.progress = progressOf(state)
+		j.report = report
+		j.err = err
+		j.mu.Unlock()
+	}()
+
+	return j
+}
+
+func progressOf(state JobState) JobProgress {
+	return JobProgress{
+		Status:    state.Status,
+		Processed: state.Processed,
+		Assigned:  state.Assigned,
+		Skipped:   state.Skipped,
+		Failed:    state.Failed,
+		Repaired:  state.Repaired,
+	}
+}
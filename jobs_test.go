@@ -0,0 +1,78 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestImportJob(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	var ids []uuid.UUID
+	var csv strings.Builder
+	for i := 0; i < 6; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		fmt.Fprintf(&csv, "%s,role-a\n", id)
+	}
+
+	// the job stops after the first batch, the context is canceled while reading the second
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	job, err := auth.StartImportJob(ctx, &cancelingReader{r: strings.NewReader(csv.String()), after: 3, cancel: cancel}, authority.ImportOptions{BatchSize: 2})
+	if err != nil {
+		t.Error("unexpected error while starting import job.", err)
+	}
+	_, err = job.Wait()
+	if !errors.Is(err, context.Canceled) {
+		t.Error("expecting the job to be canceled")
+	}
+	progress := job.Progress()
+	if progress.Status != authority.JobCanceled || progress.Processed != 2 || progress.Assigned != 2 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+
+	// resume from the checkpoint
+	job, err = auth.ResumeImportJob(context.Background(), job.ID(), strings.NewReader(csv.String()), authority.ImportOptions{BatchSize: 2})
+	if err != nil {
+		t.Error("unexpected error while resuming import job.", err)
+	}
+	report, err := job.Wait()
+	if err != nil {
+		t.Error("unexpected error while running import job.", err)
+	}
+	if report.Rows != 4 {
+		t.Error("expecting the processed rows to be skipped")
+	}
+	state, _ := auth.GetJob(job.ID())
+	if state.Status != authority.JobCompleted || state.Processed != 6 || state.Assigned != 6 {
+		t.Errorf("unexpected job state %+v", state)
+	}
+	for _, id := range ids {
+		ok, _ := auth.CheckRole(id, "role-a")
+		if !ok {
+			t.Error("expecting the imported role to be assigned")
+		}
+	}
+
+	// completed jobs cannot be resumed
+	_, err = auth.ResumeImportJob(context.Background(), job.ID(), strings.NewReader(csv.String()), authority.ImportOptions{})
+	if !errors.Is(err, authority.ErrJobNotResumable) {
+		t.Error("expecting an error when resuming a completed job")
+	}
+
+	// clean up
+	db.Where("id = ?", job.ID()).Delete(authority.JobState{})
+	db.Where("user_id IN (?)", ids).Delete(authority.UserRole{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
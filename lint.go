@@ -0,0 +1,71 @@
+package authority
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	referencesMu sync.Mutex
+	references   = map[string]bool{}
+)
+
+// ReferencePermission registers a permission name referenced by the application
+// and returns it, it's meant to declare the permissions as package variables
+//
+//	var PostsEdit = authority.ReferencePermission("posts.edit")
+func ReferencePermission(permName string) string {
+	referencesMu.Lock()
+	defer referencesMu.Unlock()
+	references[permName] = true
+
+	return permName
+}
+
+// ReferencedPermissions returns the sorted permission names registered with ReferencePermission
+func ReferencedPermissions() []string {
+	referencesMu.Lock()
+	defer referencesMu.Unlock()
+
+	names := []string{}
+	for name := range references {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// MissingPermissionsError lists the referenced permissions missing from the database
+type MissingPermissionsError struct {
+	Names []string
+}
+
+// Error returns the error message
+func (e *MissingPermissionsError) Error() string {
+	return "missing permissions " + strings.Join(e.Names, ", ")
+}
+
+// ValidatePolicyReferences verifies that every required permission exists in the database
+// it's meant to be called on startup to fail fast on the typos, usually with ReferencedPermissions()
+// it returns ErrPermissionNotFound wrapping a *MissingPermissionsError with the missing names
+func (a *Authority) ValidatePolicyReferences(requiredPerms []string) error {
+	exist, err := a.PermissionsExist(requiredPerms)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for name, found := range exist {
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+
+	return wrapError(ErrPermissionNotFound, &MissingPermissionsError{Names: missing})
+}
@@ -0,0 +1,39 @@
+package authority_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faozimipa/authority"
+)
+
+func TestValidatePolicyReferences(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	permA := authority.ReferencePermission("permission-a")
+	authority.ReferencePermission("permission-x")
+	if !sliceHasString(authority.ReferencedPermissions(), permA) {
+		t.Error("expecting the referenced permission to be registered")
+	}
+
+	auth.CreatePermission("permission-a", "a description permission")
+	err := auth.ValidatePolicyReferences([]string{"permission-a"})
+	if err != nil {
+		t.Error("unexpected error while validating existing references.", err)
+	}
+
+	err = auth.ValidatePolicyReferences(authority.ReferencedPermissions())
+	if !errors.Is(err, authority.ErrPermissionNotFound) {
+		t.Error("expecting an error for a missing permission")
+	}
+	var missing *authority.MissingPermissionsError
+	if !errors.As(err, &missing) || len(missing.Names) != 1 || missing.Names[0] != "permission-x" {
+		t.Errorf("expecting the missing names, got %v", err)
+	}
+
+	// clean up
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+}
@@ -0,0 +1,301 @@
+package authority
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Loader memoizes the role and permission lookups for the lifetime of a request
+// the lookups are shared across the users and permissions checked by the request
+// it must not outlive the request as it doesn't see the changes made after a lookup
+type Loader struct {
+	auth *Authority
+	ctx  context.Context
+
+	mu          sync.Mutex
+	roles       map[string]*Role
+	rolesByID   map[uint]*Role
+	perms       map[string]*Permission
+	userRoleIDs map[uuid.UUID][]uint
+	rolePermIDs map[uint]map[uint]bool
+	impliers    map[uint][]uint
+	federated   map[string]bool
+}
+
+// NewLoader returns a loader scoped to the given context, usually a request context
+// the lookups are made within the tenant of the context
+func (a *Authority) NewLoader(ctx context.Context) *Loader {
+	return &Loader{
+		auth:        a,
+		ctx:         ctx,
+		roles:       map[string]*Role{},
+		rolesByID:   map[uint]*Role{},
+		perms:       map[string]*Permission{},
+		userRoleIDs: map[uuid.UUID][]uint{},
+		rolePermIDs: map[uint]map[uint]bool{},
+		federated:   map[string]bool{},
+	}
+}
+
+// LoaderMiddleware attaches a new loader to the context of every request
+func (a *Authority) LoaderMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := a.NewLoader(r.Context())
+		next.ServeHTTP(w, r.WithContext(WithLoader(r.Context(), l)))
+	})
+}
+
+// WithLoader returns a copy of the context carrying the loader
+func WithLoader(ctx context.Context, l *Loader) context.Context {
+	return context.WithValue(ctx, loaderKey, l)
+}
+
+// LoaderFromContext returns the loader carried by the context, nil if there is none
+func LoaderFromContext(ctx context.Context) *Loader {
+	l, _ := ctx.Value(loaderKey).(*Loader)
+	return l
+}
+
+// CheckRole checks if a role is assigned to a user
+// it returns an error if the role is not present in database
+func (l *Loader) CheckRole(userID uuid.UUID, roleName string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	role, err := l.role(roleName)
+	if err != nil {
+		return false, err
+	}
+	roleIDs, err := l.rolesOf(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, id := range roleIDs {
+		if id == role.ID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CheckPermission checks if a permission is assigned to the roles of the user
+// it returns an error if the permission is not present in the database
+// permissions of a federated resource type are checked by the owning service
+func (l *Loader) CheckPermission(userID uuid.UUID, permName string) (bool, error) {
+	ok, err := l.checkPermission(userID, permName)
+	l.auth.countCheck(permName, ok, err)
+
+	return ok, err
+}
+
+func (l *Loader) checkPermission(userID uuid.UUID, permName string) (bool, error) {
+	if c := l.auth.federationClient(permName); c != nil {
+		return l.checkFederated(c, userID, permName)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perm, err := l.permission(permName)
+	if err != nil {
+		return false, err
+	}
+	roleIDs, err := l.rolesOf(userID)
+	if err != nil {
+		return false, err
+	}
+	if err := l.loadRolePermissions(roleIDs); err != nil {
+		return false, err
+	}
+	if err := l.loadImplications(); err != nil {
+		return false, err
+	}
+
+	permIDs := append([]uint{perm.ID}, walk(perm.ID, l.impliers)...)
+	for _, roleID := range roleIDs {
+		for _, permID := range permIDs {
+			if l.rolePermIDs[roleID][permID] {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// GetUserRoles returns all user assigned roles
+func (l *Loader) GetUserRoles(userID uuid.UUID) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	roleIDs, err := l.rolesOf(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// load the missing roles at once
+	var missing []uint
+	for _, id := range roleIDs {
+		if _, found := l.rolesByID[id]; !found {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		var roles []Role
+		res := l.auth.DB.WithContext(l.ctx).Where("id IN (?)", missing).Find(&roles)
+		if res.Error != nil {
+			return nil, storeError(res.Error)
+		}
+		for i := range roles {
+			l.rolesByID[roles[i].ID] = &roles[i]
+			l.roles[roles[i].Name] = &roles[i]
+		}
+	}
+
+	var result []string
+	for _, id := range roleIDs {
+		if role, found := l.rolesByID[id]; found {
+			result = append(result, role.Name)
+		}
+	}
+
+	return result, nil
+}
+
+func (l *Loader) checkFederated(c *FederationClient, userID uuid.UUID, permName string) (bool, error) {
+	key := userID.String() + "|" + permName
+	l.mu.Lock()
+	ok, found := l.federated[key]
+	l.mu.Unlock()
+	if found {
+		return ok, nil
+	}
+
+	ok, err := c.CheckPermissionContext(l.ctx, userID, permName)
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	l.federated[key] = ok
+	l.mu.Unlock()
+
+	return ok, nil
+}
+
+// role returns the memoized role, a nil entry records a missing role
+func (l *Loader) role(roleName string) (*Role, error) {
+	role, found := l.roles[roleName]
+	if !found {
+		var r Role
+		res := l.auth.DB.WithContext(l.ctx).Where("name = ?", roleName).First(&r)
+		if res.Error != nil && !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return nil, storeError(res.Error)
+		}
+		if res.Error == nil {
+			role = &r
+			l.rolesByID[r.ID] = role
+		}
+		l.roles[roleName] = role
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+
+	return role, nil
+}
+
+// permission returns the memoized permission, a nil entry records a missing permission
+func (l *Loader) permission(permName string) (*Permission, error) {
+	perm, found := l.perms[permName]
+	if !found {
+		var p Permission
+		res := l.auth.DB.WithContext(l.ctx).Where("name = ?", permName).First(&p)
+		if res.Error != nil && !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return nil, storeError(res.Error)
+		}
+		if res.Error == nil {
+			perm = &p
+		}
+		l.perms[permName] = perm
+	}
+	if perm == nil {
+		return nil, ErrPermissionNotFound
+	}
+
+	return perm, nil
+}
+
+// rolesOf returns the memoized ids of the roles assigned to the user
+func (l *Loader) rolesOf(userID uuid.UUID) ([]uint, error) {
+	if ids, found := l.userRoleIDs[userID]; found {
+		return ids, nil
+	}
+
+	var userRoles []UserRole
+	res := l.auth.userRoles(l.ctx).Where("user_id = ?", userID).Find(&userRoles)
+	if res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	ids := []uint{}
+	for _, r := range userRoles {
+		ids = append(ids, r.RoleID)
+	}
+	l.userRoleIDs[userID] = ids
+
+	return ids, nil
+}
+
+// loadRolePermissions loads the permissions of the roles not loaded yet at once
+func (l *Loader) loadRolePermissions(roleIDs []uint) error {
+	var missing []uint
+	for _, id := range roleIDs {
+		if _, found := l.rolePermIDs[id]; !found {
+			missing = append(missing, id)
+			l.rolePermIDs[id] = map[uint]bool{}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var rolePerms []RolePermission
+	res := l.auth.DB.WithContext(l.ctx).Where("role_id IN (?)", missing).Find(&rolePerms)
+	if res.Error != nil {
+		for _, id := range missing {
+			delete(l.rolePermIDs, id)
+		}
+		return storeError(res.Error)
+	}
+	for _, rp := range rolePerms {
+		l.rolePermIDs[rp.RoleID][rp.PermissionID] = true
+	}
+
+	return nil
+}
+
+// loadImplications loads the permission implications once
+func (l *Loader) loadImplications() error {
+	if l.impliers != nil {
+		return nil
+	}
+
+	edges, err := l.auth.loadImplications()
+	if err != nil {
+		return err
+	}
+	l.impliers = map[uint][]uint{}
+	for _, e := range edges {
+		l.impliers[e.ImpliedPermissionID] = append(l.impliers[e.ImpliedPermissionID], e.PermissionID)
+	}
+
+	return nil
+}
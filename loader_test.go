@@ -0,0 +1,81 @@
+package authority_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestLoader(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.CreatePermission("permission-b", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	id := uuid.New()
+	id2 := uuid.New()
+	auth.AssignRole(id, "role-a")
+
+	var l *authority.Loader
+	h := auth.LoaderMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l = authority.LoaderFromContext(r.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if l == nil {
+		t.Fatal("expecting a loader to be attached to the request")
+	}
+
+	ok, err := l.CheckPermission(id, "permission-a")
+	if err != nil {
+		t.Error("unexpected error while checking permission.", err)
+	}
+	if !ok {
+		t.Error("expecting true to be returned")
+	}
+	ok, _ = l.CheckPermission(id, "permission-b")
+	if ok {
+		t.Error("expecting false for a not assigned permission")
+	}
+	ok, _ = l.CheckPermission(id2, "permission-a")
+	if ok {
+		t.Error("expecting false for a user without roles")
+	}
+	ok, _ = l.CheckRole(id, "role-a")
+	if !ok {
+		t.Error("expecting the role to be assigned")
+	}
+	roles, _ := l.GetUserRoles(id)
+	if len(roles) != 1 || roles[0] != "role-a" {
+		t.Error("expecting the user roles to be returned")
+	}
+	_, err = l.CheckPermission(id, "permission-aa")
+	if err == nil {
+		t.Error("expecting an error when checking a missing permission")
+	}
+
+	// lookups are memoized for the lifetime of the loader
+	auth.RevokeRole(id, "role-a")
+	ok, _ = l.CheckPermission(id, "permission-a")
+	if !ok {
+		t.Error("expecting the memoized lookups to be used")
+	}
+	ok, _ = auth.NewLoader(context.Background()).CheckPermission(id, "permission-a")
+	if ok {
+		t.Error("expecting a new loader to see the changes")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name IN (?)", []string{"permission-a", "permission-b"}).Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
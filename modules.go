@@ -0,0 +1,187 @@
+package authority
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ModuleRole describes a role installed by a module along with its permissions
+type ModuleRole struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// ModulePermission describes a permission installed by a module
+type ModulePermission struct {
+	Name        string
+	Description string
+}
+
+// RegisterModule installs the roles and permissions of a module at once
+// it's safe to call it on every startup, the missing artifacts are created,
+// the descriptions are updated and the missing role permissions are assigned
+// the roles may be assigned permissions of other modules or of the application,
+// it returns an error if any of them is not present in the database
+// it returns an error if an artifact is owned by another module or by the application
+func (a *Authority) RegisterModule(name string, roles []ModuleRole, permissions []ModulePermission) error {
+	return a.RegisterModuleContext(context.Background(), name, roles, permissions)
+}
+
+// RegisterModuleContext installs a module on behalf of the actor of the context
+func (a *Authority) RegisterModuleContext(ctx context.Context, name string, roles []ModuleRole, permissions []ModulePermission) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRegisterModule, Name: name}); err != nil {
+		return err
+	}
+
+	err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		for _, p := range permissions {
+			var perm Permission
+			res := tx.Where("name = ?", p.Name).First(&perm)
+			if res.Error != nil && !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return storeError(res.Error)
+			}
+			if res.Error != nil {
+				res = tx.Create(&Permission{Name: p.Name, Description: p.Description, Module: name})
+			} else if perm.Module != name {
+				return ErrModuleConflict
+			} else {
+				res = tx.Model(&perm).Update("description", p.Description)
+			}
+			if res.Error != nil {
+				return storeError(res.Error)
+			}
+		}
+
+		for _, r := range roles {
+			var role Role
+			res := tx.Where("name = ?", r.Name).First(&role)
+			if res.Error != nil && !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return storeError(res.Error)
+			}
+			if res.Error != nil {
+				role = Role{Name: r.Name, Description: r.Description, Module: name}
+				res = tx.Create(&role)
+			} else if role.Module != name {
+				return ErrModuleConflict
+			} else {
+				res = tx.Model(&role).Update("description", r.Description)
+			}
+			if res.Error != nil {
+				return storeError(res.Error)
+			}
+
+			for _, permName := range r.Permissions {
+				var perm Permission
+				res := tx.Where("name = ?", permName).First(&perm)
+				if res.Error != nil {
+					if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+						return ErrPermissionNotFound
+					}
+					return storeError(res.Error)
+				}
+
+				// ignore any assigned permission
+				var c int64
+				res = tx.Model(RolePermission{}).Where("role_id = ?", role.ID).Where("permission_id = ?", perm.ID).Count(&c)
+				if res.Error != nil {
+					return storeError(res.Error)
+				}
+				if c == 0 {
+					if res := tx.Create(&RolePermission{RoleID: role.ID, PermissionID: perm.ID}); res.Error != nil {
+						return storeError(res.Error)
+					}
+					if err := a.recordEvents(tx, rolePermissionEvent(EventPermissionAssigned, role, perm)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// RemoveModule deletes all the roles and permissions installed by a module at once
+// the roles are revoked from the users and the permissions from the roles before being deleted
+// removing a module that is not installed does nothing
+func (a *Authority) RemoveModule(name string) error {
+	return a.RemoveModuleContext(context.Background(), name)
+}
+
+// RemoveModuleContext deletes a module on behalf of the actor of the context
+func (a *Authority) RemoveModuleContext(ctx context.Context, name string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRemoveModule, Name: name}); err != nil {
+		return err
+	}
+
+	err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		var roles []Role
+		if res := tx.Where("module = ?", name).Find(&roles); res.Error != nil {
+			return storeError(res.Error)
+		}
+		var perms []Permission
+		if res := tx.Where("module = ?", name).Find(&perms); res.Error != nil {
+			return storeError(res.Error)
+		}
+		var roleIDs []uint
+		for _, r := range roles {
+			roleIDs = append(roleIDs, r.ID)
+		}
+		var permIDs []uint
+		for _, p := range perms {
+			permIDs = append(permIDs, p.ID)
+		}
+
+		if len(roleIDs) > 0 {
+			if res := tx.Where("role_id IN (?)", roleIDs).Delete(UserRole{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res := tx.Where("role_id IN (?)", roleIDs).Delete(RolePermission{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res := tx.Where("id IN (?)", roleIDs).Delete(Role{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			var events []AssignmentEvent
+			for _, r := range roles {
+				events = append(events, AssignmentEvent{Action: EventRoleDeleted, RoleID: r.ID, RoleName: r.Name})
+			}
+			if err := a.recordEvents(tx, events...); err != nil {
+				return err
+			}
+		}
+
+		if len(permIDs) > 0 {
+			if res := tx.Where("permission_id IN (?)", permIDs).Delete(RolePermission{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res := tx.Where("permission_id IN (?)", permIDs).Or("implied_permission_id IN (?)", permIDs).Delete(PermissionImplication{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res := tx.Where("id IN (?)", permIDs).Delete(Permission{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if err := a.recordEvents(tx, permissionDeletedEvents(perms)...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
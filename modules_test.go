@@ -0,0 +1,78 @@
+package authority_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestModules(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	roles := []authority.ModuleRole{
+		{Name: "billing-admin", Description: "a description role", Permissions: []string{"billing.view", "billing.edit"}},
+	}
+	perms := []authority.ModulePermission{
+		{Name: "billing.view", Description: "a description permission"},
+		{Name: "billing.edit", Description: "a description permission"},
+	}
+
+	// installing twice is safe
+	err := auth.RegisterModule("billing", roles, perms)
+	if err != nil {
+		t.Error("unexpected error while registering module.", err)
+	}
+	err = auth.RegisterModule("billing", roles, perms)
+	if err != nil {
+		t.Error("unexpected error while registering module again.", err)
+	}
+	var c int64
+	db.Model(authority.Permission{}).Where("module = ?", "billing").Count(&c)
+	if c != 2 {
+		t.Error("expecting the module permissions to be installed once")
+	}
+
+	id := uuid.New()
+	auth.AssignRole(id, "billing-admin")
+	ok, _ := auth.CheckPermission(id, "billing.edit")
+	if !ok {
+		t.Error("expecting the module role permissions to be assigned")
+	}
+
+	// artifacts owned by another module are refused
+	err = auth.RegisterModule("shipping", nil, []authority.ModulePermission{{Name: "billing.view"}})
+	if !errors.Is(err, authority.ErrModuleConflict) {
+		t.Error("expecting an error when installing an artifact of another module")
+	}
+
+	// uninstall
+	err = auth.RemoveModule("billing")
+	if err != nil {
+		t.Error("unexpected error while removing module.", err)
+	}
+	db.Model(authority.Permission{}).Where("module = ?", "billing").Count(&c)
+	if c != 0 {
+		t.Error("expecting the module permissions to be deleted")
+	}
+	db.Model(authority.UserRole{}).Where("user_id = ?", id).Count(&c)
+	if c != 0 {
+		t.Error("expecting the module roles to be revoked")
+	}
+	db.Model(authority.AssignmentEvent{}).Where("action = ?", authority.EventRoleDeleted).Where("role_name = ?", "billing-admin").Count(&c)
+	if c != 1 {
+		t.Error("expecting the role delete to be recorded with its name")
+	}
+	db.Model(authority.AssignmentEvent{}).Where("action = ?", authority.EventPermissionDeleted).Where("permission_name IN (?)", []string{"billing.view", "billing.edit"}).Count(&c)
+	if c != 2 {
+		t.Error("expecting the permission deletes to be recorded with their names")
+	}
+
+	// clean up
+	db.Where("role_name = ?", "billing-admin").Delete(authority.AssignmentEvent{})
+	db.Where("permission_name IN (?)", []string{"billing.view", "billing.edit"}).Delete(authority.AssignmentEvent{})
+}
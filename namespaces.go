@@ -0,0 +1,261 @@
+package authority
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RegisterNamespace stores a permission namespace in the database
+// the namespace groups the permissions whose names start with its name and a dot,
+// for example "billing" groups "billing.invoices.view"
+// it's safe to call it on every startup
+func (a *Authority) RegisterNamespace(name string, description string) error {
+	return a.RegisterNamespaceContext(context.Background(), name, description)
+}
+
+// RegisterNamespaceContext stores a namespace on behalf of the actor of the context
+func (a *Authority) RegisterNamespaceContext(ctx context.Context, name string, description string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRegisterNamespace, Name: name}); err != nil {
+		return err
+	}
+	db := a.DB.WithContext(ctx)
+
+	name = strings.TrimSuffix(name, ".")
+	var ns PermissionNamespace
+	res := db.Where("name = ?", name).First(&ns)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return storeError(db.Create(&PermissionNamespace{Name: name, Description: description}).Error)
+		}
+	}
+
+	return storeError(res.Error)
+}
+
+// GetNamespaces returns all registered namespaces
+func (a *Authority) GetNamespaces() ([]string, error) {
+	var result []string
+	var namespaces []PermissionNamespace
+	res := a.DB.Find(&namespaces)
+	if res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	for _, ns := range namespaces {
+		result = append(result, ns.Name)
+	}
+
+	return result, nil
+}
+
+// GetNamespacePermissions returns the permissions of a namespace
+// it returns an error if the namespace is not registered
+func (a *Authority) GetNamespacePermissions(name string) ([]string, error) {
+	perms, err := a.namespacePermissions(a.DB, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, p := range perms {
+		result = append(result, p.Name)
+	}
+
+	return result, nil
+}
+
+// AssignNamespace assigns all the permissions of a namespace to a role at once
+// it returns an error if the role is not present in the database
+// it returns an error if the namespace is not registered
+func (a *Authority) AssignNamespace(roleName string, name string) error {
+	return a.AssignNamespaceContext(context.Background(), roleName, name)
+}
+
+// AssignNamespaceContext assigns a namespace to a role on behalf of the actor of the context
+func (a *Authority) AssignNamespaceContext(ctx context.Context, roleName string, name string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpAssignNamespace, Role: roleName, Name: name}); err != nil {
+		return err
+	}
+
+	err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		role, err := a.findRole(roleName)
+		if err != nil {
+			return err
+		}
+		perms, err := a.namespacePermissions(tx, name)
+		if err != nil {
+			return err
+		}
+
+		// ignore any assigned permission
+		var assigned []RolePermission
+		if res := tx.Where("role_id = ?", role.ID).Find(&assigned); res.Error != nil {
+			return storeError(res.Error)
+		}
+		isAssigned := map[uint]bool{}
+		for _, rp := range assigned {
+			isAssigned[rp.PermissionID] = true
+		}
+
+		var events []AssignmentEvent
+		for _, p := range perms {
+			if isAssigned[p.ID] {
+				continue
+			}
+			if res := tx.Create(&RolePermission{RoleID: role.ID, PermissionID: p.ID}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			events = append(events, rolePermissionEvent(EventPermissionAssigned, role, p))
+		}
+
+		return a.recordEvents(tx, events...)
+	})
+	if err != nil {
+		return err
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// RevokeNamespace revokes all the permissions of a namespace from a role at once
+// it returns an error if the role is not present in the database
+// it returns an error if the namespace is not registered
+func (a *Authority) RevokeNamespace(roleName string, name string) error {
+	return a.RevokeNamespaceContext(context.Background(), roleName, name)
+}
+
+// RevokeNamespaceContext revokes a namespace from a role on behalf of the actor of the context
+func (a *Authority) RevokeNamespaceContext(ctx context.Context, roleName string, name string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRevokeNamespace, Role: roleName, Name: name}); err != nil {
+		return err
+	}
+
+	err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		role, err := a.findRole(roleName)
+		if err != nil {
+			return err
+		}
+		ids, err := a.namespacePermissionIDs(tx, name)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		var revoked []uint
+		res := tx.Model(RolePermission{}).Where("role_id = ?", role.ID).Where("permission_id IN (?)", ids).Pluck("permission_id", &revoked)
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		res = tx.Where("role_id = ?", role.ID).Where("permission_id IN (?)", ids).Delete(RolePermission{})
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+
+		perms, err := permissionsByID(tx, revoked)
+		if err != nil {
+			return err
+		}
+		var events []AssignmentEvent
+		for _, id := range revoked {
+			events = append(events, rolePermissionEvent(EventPermissionRevoked, role, perms[id]))
+		}
+		return a.recordEvents(tx, events...)
+	})
+	if err != nil {
+		return err
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// DeleteNamespace deletes a namespace along with all its permissions at once
+// the permissions are revoked from all roles before being deleted
+// it returns an error if the namespace is not registered
+func (a *Authority) DeleteNamespace(name string) error {
+	return a.DeleteNamespaceContext(context.Background(), name)
+}
+
+// DeleteNamespaceContext deletes a namespace on behalf of the actor of the context
+func (a *Authority) DeleteNamespaceContext(ctx context.Context, name string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpDeleteNamespace, Name: name}); err != nil {
+		return err
+	}
+
+	err := a.transaction(a.DB.WithContext(ctx), func(tx *gorm.DB) error {
+		perms, err := a.namespacePermissions(tx, name)
+		if err != nil {
+			return err
+		}
+		var ids []uint
+		for _, p := range perms {
+			ids = append(ids, p.ID)
+		}
+
+		if len(ids) > 0 {
+			if res := tx.Where("permission_id IN (?)", ids).Delete(RolePermission{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res := tx.Where("permission_id IN (?)", ids).Or("implied_permission_id IN (?)", ids).Delete(PermissionImplication{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res := tx.Where("id IN (?)", ids).Delete(Permission{}); res.Error != nil {
+				return storeError(res.Error)
+			}
+			if err := a.recordEvents(tx, permissionDeletedEvents(perms)...); err != nil {
+				return err
+			}
+		}
+
+		res := tx.Where("name = ?", strings.TrimSuffix(name, ".")).Delete(PermissionNamespace{})
+		return storeError(res.Error)
+	})
+	if err != nil {
+		return err
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// namespacePermissions returns the permissions of a registered namespace
+func (a *Authority) namespacePermissions(db *gorm.DB, name string) ([]Permission, error) {
+	name = strings.TrimSuffix(name, ".")
+	var ns PermissionNamespace
+	res := db.Where("name = ?", name).First(&ns)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrNamespaceNotFound
+		}
+		return nil, storeError(res.Error)
+	}
+
+	var perms []Permission
+	res = db.Where("name LIKE ? ESCAPE '!'", likePrefix(name+".")).Find(&perms)
+	if res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	return perms, nil
+}
+
+func (a *Authority) namespacePermissionIDs(db *gorm.DB, name string) ([]uint, error) {
+	perms, err := a.namespacePermissions(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, p := range perms {
+		ids = append(ids, p.ID)
+	}
+
+	return ids, nil
+}
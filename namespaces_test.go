@@ -0,0 +1,83 @@
+package authority_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestNamespaces(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	err := auth.RegisterNamespace("billing.", "billing module")
+	if err != nil {
+		t.Error("unexpected error while registering namespace.", err)
+	}
+	auth.RegisterNamespace("billing", "billing module")
+	auth.CreatePermission("billing.invoices.view", "a description permission")
+	auth.CreatePermission("billing.invoices.edit", "a description permission")
+	auth.CreatePermission("billingx.view", "a description permission")
+	auth.CreateRole("role-a", "a description role")
+	id := uuid.New()
+	auth.AssignRole(id, "role-a")
+
+	namespaces, _ := auth.GetNamespaces()
+	if len(namespaces) != 1 || namespaces[0] != "billing" {
+		t.Error("expecting the namespace to be registered once")
+	}
+	perms, _ := auth.GetNamespacePermissions("billing")
+	if len(perms) != 2 || sliceHasString(perms, "billingx.view") {
+		t.Error("expecting the namespace permissions to be returned")
+	}
+	_, err = auth.GetNamespacePermissions("shipping")
+	if !errors.Is(err, authority.ErrNamespaceNotFound) {
+		t.Error("expecting an error for a missing namespace")
+	}
+
+	// assign and revoke the whole namespace
+	err = auth.AssignNamespace("role-a", "billing")
+	if err != nil {
+		t.Error("unexpected error while assigning namespace.", err)
+	}
+	ok, _ := auth.CheckPermission(id, "billing.invoices.edit")
+	if !ok {
+		t.Error("expecting the namespace permissions to be assigned")
+	}
+	err = auth.RevokeNamespace("role-a", "billing")
+	if err != nil {
+		t.Error("unexpected error while revoking namespace.", err)
+	}
+	ok, _ = auth.CheckPermission(id, "billing.invoices.view")
+	if ok {
+		t.Error("expecting the namespace permissions to be revoked")
+	}
+
+	// uninstall the namespace
+	auth.AssignNamespace("role-a", "billing")
+	err = auth.DeleteNamespace("billing")
+	if err != nil {
+		t.Error("unexpected error while deleting namespace.", err)
+	}
+	var c int64
+	db.Model(authority.Permission{}).Where("name LIKE ?", "billing.%").Count(&c)
+	if c != 0 {
+		t.Error("expecting the namespace permissions to be deleted")
+	}
+	namespaces, _ = auth.GetNamespaces()
+	if len(namespaces) != 0 {
+		t.Error("expecting the namespace to be deleted")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name = ?", "billingx.view").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
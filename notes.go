@@ -0,0 +1,62 @@
+package authority
+
+// AssignmentNote is the justification of an assignment
+type AssignmentNote struct {
+	Reason    string `json:"reason,omitempty"`
+	TicketRef string `json:"ticket_ref,omitempty"`
+}
+
+// AssignOption sets the justification of an assignment
+type AssignOption func(*AssignmentNote)
+
+// WithReason sets the reason of the assignment
+func WithReason(reason string) AssignOption {
+	return func(n *AssignmentNote) {
+		n.Reason = reason
+	}
+}
+
+// WithTicketRef sets the reference of the ticket approving the assignment
+func WithTicketRef(ref string) AssignOption {
+	return func(n *AssignmentNote) {
+		n.TicketRef = ref
+	}
+}
+
+// newNote returns the note set by the options
+func newNote(opts []AssignOption) AssignmentNote {
+	var n AssignmentNote
+	for _, opt := range opts {
+		opt(&n)
+	}
+
+	return n
+}
+
+// sealNote encrypts the note fields if an encryptor is set
+func (a *Authority) sealNote(n AssignmentNote) (AssignmentNote, error) {
+	reason, err := a.seal(n.Reason)
+	if err != nil {
+		return n, err
+	}
+	ticketRef, err := a.seal(n.TicketRef)
+	if err != nil {
+		return n, err
+	}
+
+	return AssignmentNote{Reason: reason, TicketRef: ticketRef}, nil
+}
+
+// openNote decrypts the note fields sealed by sealNote
+func (a *Authority) openNote(n AssignmentNote) (AssignmentNote, error) {
+	reason, err := a.open(n.Reason)
+	if err != nil {
+		return n, err
+	}
+	ticketRef, err := a.open(n.TicketRef)
+	if err != nil {
+		return n, err
+	}
+
+	return AssignmentNote{Reason: reason, TicketRef: ticketRef}, nil
+}
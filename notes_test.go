@@ -0,0 +1,85 @@
+package authority_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestAssignmentNotes(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+	e, _ := authority.NewAESEncryptor(bytes.Repeat([]byte("k"), 32))
+	auth.SetEncryptor(e)
+	defer auth.SetEncryptor(nil)
+
+	// the privileged grants require a ticket
+	auth.SetMutationHook(func(ctx context.Context, m authority.Mutation) error {
+		if m.Operation == authority.OpAssignRole && m.Role == "role-admin" && m.Note.TicketRef == "" {
+			return errors.New("a ticket is required")
+		}
+		return nil
+	})
+	defer auth.SetMutationHook(nil)
+
+	since := time.Now().Truncate(time.Millisecond)
+	auth.CreateRole("role-admin", "an admin role")
+	auth.CreatePermission("permission-a", "a description permission")
+	userID := uuid.New()
+	err := auth.AssignRole(userID, "role-admin")
+	if !errors.Is(err, authority.ErrMutationRejected) {
+		t.Error("expecting the grant without a ticket to be rejected")
+	}
+	err = auth.AssignRole(userID, "role-admin", authority.WithReason("on call rotation"), authority.WithTicketRef("OPS-1"))
+	if err != nil {
+		t.Error("unexpected error while assigning role with a note.", err)
+	}
+	err = auth.AssignPermissions("role-admin", []string{"permission-a"}, authority.WithReason("admins manage a"))
+	if err != nil {
+		t.Error("unexpected error while assigning permissions with a note.", err)
+	}
+
+	// the notes are stored encrypted
+	var ur authority.UserRole
+	db.Where("user_id = ?", userID).First(&ur)
+	if !strings.HasPrefix(ur.TicketRef, "enc:") || strings.Contains(ur.Reason, "on call") {
+		t.Error("expecting the note of the assignment to be encrypted")
+	}
+
+	// and exported decrypted
+	var buf bytes.Buffer
+	if err := auth.ExportEvents(&buf, since); err != nil {
+		t.Error("unexpected error while exporting the events.", err)
+	}
+	var events []authority.ChangeEvent
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var ev authority.ChangeEvent
+		dec.Decode(&ev)
+		events = append(events, ev)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expecting 2 exported events, got %d", len(events))
+	}
+	if events[0].Reason != "on call rotation" || events[0].TicketRef != "OPS-1" {
+		t.Errorf("unexpected note of the role assignment %+v", events[0])
+	}
+	if events[1].Reason != "admins manage a" {
+		t.Errorf("unexpected note of the permission assignment %+v", events[1])
+	}
+
+	// clean up
+	auth.SetMutationHook(nil)
+	auth.ForceDeleteRole(context.Background(), "role-admin")
+	auth.DeletePermission("permission-a")
+	db.Where("user_id = ?", userID).Delete(authority.AssignmentEvent{})
+}
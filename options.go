@@ -0,0 +1,93 @@
+package authority
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Logger receives the errors that cannot be returned to the caller
+// it's satisfied by *log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Option sets an option of NewWithOptions
+type Option func(*Options)
+
+// WithDB sets the database, it's required
+func WithDB(db *gorm.DB) Option {
+	return func(o *Options) {
+		o.DB = db
+	}
+}
+
+// WithPrefix sets the prefix of the table names, it's required
+func WithPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.TablesPrefix = prefix
+	}
+}
+
+// WithCache enables caching the user checks for the given duration
+func WithCache(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.CacheTTL = ttl
+	}
+}
+
+// WithLogger sets the logger receiving the errors that cannot be returned
+func WithLogger(l Logger) Option {
+	return func(o *Options) {
+		o.Logger = l
+	}
+}
+
+// WithReadOnly rejects all the mutations and skips the migrations
+func WithReadOnly() Option {
+	return func(o *Options) {
+		o.ReadOnly = true
+	}
+}
+
+// WithMergeDuplicateNames merges the roles and the permissions sharing a name before migrating
+func WithMergeDuplicateNames() Option {
+	return func(o *Options) {
+		o.MergeDuplicateNames = true
+	}
+}
+
+// Validate checks the options
+// it returns ErrInvalidOptions wrapping the reason if an option is missing or invalid
+func (o Options) Validate() error {
+	if o.DB == nil {
+		return wrapError(ErrInvalidOptions, errors.New("the db is required"))
+	}
+	if o.TablesPrefix == "" {
+		return wrapError(ErrInvalidOptions, errors.New("the tables prefix is required"))
+	}
+	if o.CacheTTL < 0 {
+		return wrapError(ErrInvalidOptions, errors.New("the cache ttl cannot be negative"))
+	}
+
+	return nil
+}
+
+// NewWithOptions initiates authority from the given options
+// it returns the errors of NewWithError
+func NewWithOptions(opts ...Option) (*Authority, error) {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return NewWithError(o)
+}
+
+// logf logs an error that cannot be returned if a logger is set
+func (a *Authority) logf(format string, v ...interface{}) {
+	if a.logger != nil {
+		a.logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,66 @@
+package authority_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	_, err := authority.NewWithOptions(authority.WithPrefix("authority_"))
+	if !errors.Is(err, authority.ErrInvalidOptions) {
+		t.Error("expecting an error without a db")
+	}
+	_, err = authority.NewWithOptions(authority.WithDB(db))
+	if !errors.Is(err, authority.ErrInvalidOptions) {
+		t.Error("expecting an error without a tables prefix")
+	}
+
+	auth, err := authority.NewWithOptions(
+		authority.WithDB(db),
+		authority.WithPrefix("authority_"),
+		authority.WithCache(time.Minute),
+	)
+	if err != nil {
+		t.Error("unexpected error while initiating with options.", err)
+	}
+	err = auth.CreateRole("role-a", "a description role")
+	if err != nil {
+		t.Error("unexpected error while creating role.", err)
+	}
+
+	// clean up
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestNewWithError(t *testing.T) {
+	auth, err := authority.NewWithError(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+	if err != nil {
+		t.Error("unexpected error while initiating.", err)
+	}
+	if auth == nil {
+		t.Error("expecting the instance to be returned")
+	}
+
+	_, err = authority.NewWithError(authority.Options{TablesPrefix: "authority_"})
+	if !errors.Is(err, authority.ErrInvalidOptions) {
+		t.Error("expecting an error without a db")
+	}
+
+	// a database that cannot be reached
+	unreachable, _ := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "root:@tcp(127.0.0.1:1)/db_test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true})
+	_, err = authority.NewWithError(authority.Options{TablesPrefix: "authority_", DB: unreachable})
+	if !errors.Is(err, authority.ErrStoreUnavailable) {
+		t.Error("expecting an error when the database cannot be reached", err)
+	}
+}
@@ -0,0 +1,113 @@
+package authority
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SetRoleOwner sets the owner of a role, the owner can administer the role
+// a nil owner id removes the owner
+// it returns an error if the role is not present in the database
+func (a *Authority) SetRoleOwner(roleName string, ownerID uuid.UUID) error {
+	return a.SetRoleOwnerContext(context.Background(), roleName, ownerID)
+}
+
+// SetRoleOwnerContext sets the owner of a role on behalf of the actor of the context
+func (a *Authority) SetRoleOwnerContext(ctx context.Context, roleName string, ownerID uuid.UUID) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpSetRoleOwner, Role: roleName}); err != nil {
+		return err
+	}
+
+	role, err := a.findRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	res := a.DB.WithContext(ctx).Model(&role).Update("owner_id", ownerID)
+	return storeError(res.Error)
+}
+
+// SetRoleManager sets the role whose holders can administer the given role
+// for example a "team-a-lead" role managing the "team-a-member" role
+// an empty manager role name removes the manager
+// it returns an error if any of the roles is not present in the database
+func (a *Authority) SetRoleManager(roleName string, managerRoleName string) error {
+	return a.SetRoleManagerContext(context.Background(), roleName, managerRoleName)
+}
+
+// SetRoleManagerContext sets the manager of a role on behalf of the actor of the context
+func (a *Authority) SetRoleManagerContext(ctx context.Context, roleName string, managerRoleName string) error {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpSetRoleManager, Role: roleName}); err != nil {
+		return err
+	}
+
+	role, err := a.findRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	var managerID uint
+	if managerRoleName != "" {
+		manager, err := a.findRole(managerRoleName)
+		if err != nil {
+			return err
+		}
+		managerID = manager.ID
+	}
+
+	res := a.DB.WithContext(ctx).Model(&role).Update("managed_by_role_id", managerID)
+	return storeError(res.Error)
+}
+
+// CanAdministerRole checks if the actor can administer the given role
+// the actor can administer the role if he owns it, holds the role managing it,
+// can administer the role managing it or has the authority.roles.manage meta permission
+// it returns an error if the role is not present in the database
+func (a *Authority) CanAdministerRole(actorID uuid.UUID, roleName string) (bool, error) {
+	role, err := a.findRole(roleName)
+	if err != nil {
+		return false, err
+	}
+
+	if err := a.Authorize(actorID, PermRolesManage); err == nil {
+		return true, nil
+	} else if !errors.Is(err, ErrForbidden) {
+		return false, err
+	}
+
+	// walk up the managing roles, guarding against cycles
+	visited := map[uint]bool{}
+	for !visited[role.ID] {
+		visited[role.ID] = true
+		if role.OwnerID != uuid.Nil && role.OwnerID == actorID {
+			return true, nil
+		}
+		if role.ManagedByRoleID == 0 {
+			return false, nil
+		}
+
+		var c int64
+		res := a.DB.Model(UserRole{}).Where("user_id = ?", actorID).Where("role_id = ?", role.ManagedByRoleID).Count(&c)
+		if res.Error != nil {
+			return false, storeError(res.Error)
+		}
+		if c > 0 {
+			return true, nil
+		}
+
+		var manager Role
+		res = a.DB.Where("id = ?", role.ManagedByRoleID).First(&manager)
+		if res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+			return false, storeError(res.Error)
+		}
+		role = manager
+	}
+
+	return false, nil
+}
@@ -0,0 +1,69 @@
+package authority_test
+
+import (
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestCanAdministerRole(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("team-member", "a description role")
+	auth.CreateRole("team-lead", "a description role")
+	auth.CreateRole("department-head", "a description role")
+
+	owner := uuid.New()
+	lead := uuid.New()
+	head := uuid.New()
+	other := uuid.New()
+	auth.AssignRole(lead, "team-lead")
+	auth.AssignRole(head, "department-head")
+
+	err := auth.SetRoleOwner("team-member", owner)
+	if err != nil {
+		t.Error("unexpected error while setting role owner.", err)
+	}
+	err = auth.SetRoleManager("team-member", "team-lead")
+	if err != nil {
+		t.Error("unexpected error while setting role manager.", err)
+	}
+	auth.SetRoleManager("team-lead", "department-head")
+
+	for _, actor := range []uuid.UUID{owner, lead, head} {
+		ok, err := auth.CanAdministerRole(actor, "team-member")
+		if err != nil {
+			t.Error("unexpected error while checking role administration.", err)
+		}
+		if !ok {
+			t.Error("expecting the actor to administer the role")
+		}
+	}
+
+	ok, _ := auth.CanAdministerRole(other, "team-member")
+	if ok {
+		t.Error("expecting false for an unrelated actor")
+	}
+	ok, _ = auth.CanAdministerRole(lead, "department-head")
+	if ok {
+		t.Error("expecting false when administering a managing role")
+	}
+
+	// missing roles
+	_, err = auth.CanAdministerRole(owner, "role-aa")
+	if err == nil {
+		t.Error("expecting an error when checking a missing role")
+	}
+	err = auth.SetRoleManager("team-member", "role-aa")
+	if err == nil {
+		t.Error("expecting an error when setting a missing manager role")
+	}
+
+	// clean up
+	db.Where("user_id IN (?)", []uuid.UUID{lead, head}).Delete(authority.UserRole{})
+	db.Where("name IN (?)", []string{"team-member", "team-lead", "department-head"}).Delete(authority.Role{})
+}
@@ -0,0 +1,100 @@
+package authority
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PartitionUserRoles hash partitions the user roles table on the user id into the given
+// number of partitions, the user lookups always filter on the user id so they only scan
+// the partition of the user, the statements by id filter on the user id as well, the
+// lookups by role scan all partitions
+// mysql alters the table in place, the user id is added to the primary key as every
+// unique key must contain the partitioning column, the id keeps an index of its own
+// as the auto increment column
+// postgres creates a partitioned copy of the table and swaps it in a transaction,
+// the previous table is kept as <table>_unpartitioned and can be dropped afterwards
+// it returns ErrPartitioningUnsupported for the other databases
+func (a *Authority) PartitionUserRoles(partitions int) error {
+	if err := a.checkMutation(context.Background(), Mutation{Operation: OpPartitionUserRoles}); err != nil {
+		return err
+	}
+	if partitions < 2 {
+		return ErrInvalidPartitions
+	}
+
+	table := a.tableName(UserRole{})
+	switch a.DB.Dialector.Name() {
+	case "mysql":
+		stmt := fmt.Sprintf("ALTER TABLE `%s` ADD INDEX `%s` (`id`), DROP PRIMARY KEY, ADD PRIMARY KEY (`id`, `user_id`) PARTITION BY KEY (`user_id`) PARTITIONS %d",
+			table, a.DB.NamingStrategy.IndexName(table, "id"), partitions)
+		return storeError(a.DB.Exec(stmt).Error)
+	case "postgres":
+		return storeError(a.DB.Transaction(func(tx *gorm.DB) error {
+			return partitionPostgres(tx, table, partitions)
+		}))
+	}
+
+	return ErrPartitioningUnsupported
+}
+
+// partitionPostgres swaps the table with a copy hash partitioned on the user id
+func partitionPostgres(tx *gorm.DB, table string, partitions int) error {
+	partitioned := table + "_partitioned"
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE "%s" (LIKE "%s" INCLUDING DEFAULTS, PRIMARY KEY (id, user_id)) PARTITION BY HASH (user_id)`, partitioned, table),
+	}
+	for i := 0; i < partitions; i++ {
+		stmts = append(stmts, fmt.Sprintf(`CREATE TABLE "%s_p%d" PARTITION OF "%s" FOR VALUES WITH (MODULUS %d, REMAINDER %d)`, table, i, partitioned, partitions, i))
+	}
+	stmts = append(stmts,
+		fmt.Sprintf(`LOCK TABLE "%s" IN EXCLUSIVE MODE`, table),
+		fmt.Sprintf(`INSERT INTO "%s" SELECT * FROM "%s"`, partitioned, table),
+	)
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	// keep the id sequence alive when the previous table is dropped
+	var sequence string
+	if err := tx.Raw("SELECT pg_get_serial_sequence(?, 'id')", table).Scan(&sequence).Error; err != nil {
+		return err
+	}
+
+	stmts = []string{
+		fmt.Sprintf(`ALTER TABLE "%s" RENAME TO "%s_unpartitioned"`, table, table),
+		fmt.Sprintf(`ALTER TABLE "%s" RENAME TO "%s"`, partitioned, table),
+	}
+	if sequence != "" {
+		stmts = append(stmts, fmt.Sprintf(`ALTER SEQUENCE %s OWNED BY "%s".id`, sequence, table))
+	}
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// userRolesOf scopes a statement to the given user roles, the user ids are part of the
+// conditions so only the partitions of the users are scanned
+func userRolesOf(tx *gorm.DB, userRoles []UserRole) *gorm.DB {
+	var ids []uint
+	users := map[uuid.UUID]bool{}
+	var userIDs []uuid.UUID
+	for _, ur := range userRoles {
+		ids = append(ids, ur.ID)
+		if !users[ur.UserID] {
+			users[ur.UserID] = true
+			userIDs = append(userIDs, ur.UserID)
+		}
+	}
+
+	return tx.Where("user_id IN (?)", userIDs).Where("id IN (?)", ids)
+}
@@ -0,0 +1,72 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestPartitionUserRoles(t *testing.T) {
+	defer authority.New(authority.Options{TablesPrefix: "authority_", DB: db})
+
+	auth := authority.New(authority.Options{
+		TablesPrefix: "partitioned_",
+		DB:           db,
+	})
+	// clean up even if partitioning fails
+	defer func() {
+		var tables []string
+		db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name LIKE ?", "partitioned\\_%").Scan(&tables)
+		for _, table := range tables {
+			db.Migrator().DropTable(table)
+		}
+	}()
+
+	err := auth.PartitionUserRoles(1)
+	if !errors.Is(err, authority.ErrInvalidPartitions) {
+		t.Error("expecting an error when partitioning into a single partition")
+	}
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	if err := auth.PartitionUserRoles(4); err != nil {
+		t.Fatal("unexpected error while partitioning the user roles.", err)
+	}
+	var keys int64
+	db.Raw("SELECT COUNT(*) FROM information_schema.key_column_usage WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = 'PRIMARY' AND column_name = 'user_id'",
+		"partitioned_user_roles").Scan(&keys)
+	if keys != 1 {
+		t.Error("expecting the user id to be part of the primary key")
+	}
+
+	// the lookups and writes keep working on the partitioned table
+	ok, err := auth.CheckPermission(userID, "permission-a")
+	if err != nil || !ok {
+		t.Error("expecting the permission to be granted after partitioning.", err)
+	}
+	other := uuid.New()
+	if err := auth.AssignRole(other, "role-a"); err != nil {
+		t.Error("unexpected error while assigning a role after partitioning.", err)
+	}
+	if _, err := auth.RevokeRole(other, "role-a"); err != nil {
+		t.Error("unexpected error while revoking a role after partitioning.", err)
+	}
+	var r authority.Role
+	db.Table("partitioned_roles").Where("name = ?", "role-a").First(&r)
+	db.Table("partitioned_user_roles").Create(&authority.UserRole{UserID: userID, RoleID: r.ID})
+	removed, err := auth.RepairAssignments(userID)
+	if err != nil || removed != 1 {
+		t.Error("expecting the duplicated assignment to be removed from the partitioned table.", removed, err)
+	}
+	report, err := auth.ForceDeleteRole(context.Background(), "role-a")
+	if err != nil || report.Revoked != 1 {
+		t.Errorf("expecting the role to be revoked from the partitioned table, got %+v %v", report, err)
+	}
+}
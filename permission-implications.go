@@ -0,0 +1,18 @@
+package authority
+
+// PermissionImplication stores that having a permission implies having another one
+type PermissionImplication struct {
+	ID                  uint
+	PermissionID        uint
+	ImpliedPermissionID uint
+}
+
+// TableName sets the table name
+func (p PermissionImplication) TableName() string {
+	return defaultPrefix() + p.table()
+}
+
+// table returns the table name without the prefix
+func (PermissionImplication) table() string {
+	return "permission_implications"
+}
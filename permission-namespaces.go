@@ -0,0 +1,19 @@
+package authority
+
+// PermissionNamespace represents the database model of permission namespaces
+// a namespace groups the permissions whose names start with its name and a dot
+type PermissionNamespace struct {
+	ID          uint
+	Name        string
+	Description string
+}
+
+// TableName sets the table name
+func (n PermissionNamespace) TableName() string {
+	return defaultPrefix() + n.table()
+}
+
+// table returns the table name without the prefix
+func (PermissionNamespace) table() string {
+	return "permission_namespaces"
+}
@@ -0,0 +1,23 @@
+package authority
+
+import "time"
+
+// PermissionUsage represents the database model of the check counters of a permission
+type PermissionUsage struct {
+	ID         uint
+	Permission string `gorm:"size:191;index"`
+	Checks     int64
+	Allowed    int64
+	Denied     int64
+	UpdatedAt  time.Time
+}
+
+// TableName sets the table name
+func (u PermissionUsage) TableName() string {
+	return defaultPrefix() + u.table()
+}
+
+// table returns the table name without the prefix
+func (PermissionUsage) table() string {
+	return "permission_usages"
+}
@@ -1,13 +1,26 @@
 package authority
 
+import (
+	"time"
+)
+
 // Permission represents the database model of permissions
 type Permission struct {
 	ID          uint
-	Name        string
+	Name        string `gorm:"size:191;uniqueIndex"`
 	Description string
+	// Module is the module that installed the permission, empty for the application permissions
+	Module    string `gorm:"size:191;not null;default:''"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // TableName sets the table name
 func (p Permission) TableName() string {
-	return tablePrefix + "permissions"
+	return defaultPrefix() + p.table()
+}
+
+// table returns the table name without the prefix
+func (Permission) table() string {
+	return "permissions"
 }
@@ -0,0 +1,77 @@
+package authority
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PostgresNotifier keeps the caches of the instances sharing a postgres database
+// coherent using LISTEN/NOTIFY
+type PostgresNotifier struct {
+	auth     *Authority
+	channel  string
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// NewPostgresNotifier listens for invalidation events on the given channel
+// and sets itself as the cache notifier of the authority instance
+// it accepts the connection string of the postgres database shared by the instances
+func NewPostgresNotifier(a *Authority, dsn string, channel string) (*PostgresNotifier, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, nil)
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	n := &PostgresNotifier{
+		auth:     a,
+		channel:  channel,
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go n.listen()
+	a.SetCacheNotifier(n)
+
+	return n, nil
+}
+
+// Notify broadcasts the invalidation event to the other instances
+func (n *PostgresNotifier) Notify(ev InvalidationEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	return storeError(n.auth.DB.Exec("SELECT pg_notify(?, ?)", n.channel, string(payload)).Error)
+}
+
+// Close stops listening for invalidation events
+func (n *PostgresNotifier) Close() error {
+	close(n.done)
+	return n.listener.Close()
+}
+
+func (n *PostgresNotifier) listen() {
+	for {
+		select {
+		case <-n.done:
+			return
+		case notification := <-n.listener.Notify:
+			if notification == nil {
+				// the connection was re-established, events might have been missed
+				n.auth.cache.invalidate(uuid.Nil)
+				continue
+			}
+
+			var ev InvalidationEvent
+			if err := json.Unmarshal([]byte(notification.Extra), &ev); err != nil {
+				continue
+			}
+			n.auth.HandleInvalidation(ev)
+		}
+	}
+}
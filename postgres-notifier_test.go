@@ -0,0 +1,67 @@
+package authority_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+func TestPostgresNotifier(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+		CacheTTL:     time.Minute,
+	})
+	n, err := authority.NewPostgresNotifier(auth, dsn, "authority_test")
+	if err != nil {
+		t.Fatal("unexpected error while listening.", err)
+	}
+	defer n.Close()
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	id := uuid.New()
+	auth.AssignRole(id, "role-a")
+	auth.CheckPermission(id, "permission-a")
+
+	// the events notified by another instance drop the cached checks
+	db.Where("user_id = ?", id).Delete(authority.UserRole{})
+	pg, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal("unexpected error while connecting to postgres.", err)
+	}
+	defer pg.Close()
+	payload, _ := json.Marshal(authority.InvalidationEvent{Origin: "another-instance", UserID: id})
+	if _, err := pg.Exec("SELECT pg_notify($1, $2)", "authority_test", string(payload)); err != nil {
+		t.Fatal("unexpected error while notifying.", err)
+	}
+	invalidated := false
+	for i := 0; i < 50 && !invalidated; i++ {
+		ok, _ := auth.CheckPermission(id, "permission-a")
+		invalidated = !ok
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !invalidated {
+		t.Error("expecting the notified event to drop the cached check")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("user_id = ?", id).Delete(authority.AssignmentEvent{})
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
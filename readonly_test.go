@@ -0,0 +1,76 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestReadOnly(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	sidecar, err := authority.NewWithOptions(
+		authority.WithDB(db),
+		authority.WithPrefix("authority_"),
+		authority.WithReadOnly(),
+	)
+	if err != nil {
+		t.Fatal("unexpected error while initiating a read only instance.", err)
+	}
+	if !sidecar.IsReadOnly() || auth.IsReadOnly() {
+		t.Error("expecting only the sidecar to be read only")
+	}
+
+	// checks and reads are answered
+	ok, err := sidecar.CheckPermission(userID, "permission-a")
+	if err != nil || !ok {
+		t.Error("expecting the permission check to be answered", err)
+	}
+	roles, _ := sidecar.GetUserRoles(userID)
+	if len(roles) != 1 || roles[0] != "role-a" {
+		t.Error("expecting the user roles to be returned")
+	}
+
+	// mutations are rejected even after unfreezing
+	sidecar.Unfreeze()
+	err = sidecar.CreateRole("role-b", "b description role")
+	if !errors.Is(err, authority.ErrReadOnly) {
+		t.Error("expecting an error when creating a role on a read only instance")
+	}
+	if authority.ErrorCodeOf(err).HTTPStatus() != http.StatusForbidden {
+		t.Error("expecting the forbidden status")
+	}
+	if _, err := sidecar.RevokeRole(userID, "role-a"); !errors.Is(err, authority.ErrReadOnly) {
+		t.Error("expecting an error when revoking a role on a read only instance")
+	}
+	if _, err := sidecar.RepairAssignments(userID); !errors.Is(err, authority.ErrReadOnly) {
+		t.Error("expecting an error when repairing on a read only instance")
+	}
+	w := sidecar.NewBatchWriter(context.Background(), authority.BatchWriterOptions{})
+	if err := w.AssignRole(userID, "role-a"); !errors.Is(err, authority.ErrReadOnly) {
+		t.Error("expecting an error when writing a batch on a read only instance")
+	}
+	w.Close()
+	ok, _ = sidecar.CheckRole(userID, "role-a")
+	if !ok {
+		t.Error("expecting the role to still be assigned")
+	}
+
+	// clean up
+	auth.RevokeRole(userID, "role-a")
+	auth.DeleteRole("role-a")
+	auth.DeletePermission("permission-a")
+	db.Where("user_id = ?", userID).Delete(authority.AssignmentEvent{})
+}
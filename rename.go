@@ -0,0 +1,208 @@
+package authority
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// tableRename is a table to be renamed along with its indexes named after it
+type tableRename struct {
+	from, to string
+	indexes  []indexRename
+}
+
+// indexRename is an index of a single column to be renamed along with its table
+type indexRename struct {
+	from, to string
+	column   string
+	unique   bool
+}
+
+// mysqlRenameIndex returns the statement renaming the index of the table on mysql, the index
+// is dropped and added in a single statement so the table is never without it
+func mysqlRenameIndex(table string, idx indexRename, from string, to string) string {
+	class := "INDEX"
+	if idx.unique {
+		class = "UNIQUE INDEX"
+	}
+
+	return fmt.Sprintf("ALTER TABLE `%s` DROP INDEX `%s`, ADD %s `%s` (`%s`)", table, from, class, to, idx.column)
+}
+
+// renameLockTimeout is the number of seconds the rename waits for the tables used by
+// the transactions in progress on mysql, a transaction waiting for the new table names
+// while the rename waits for it fails the rename instead of blocking both
+const renameLockTimeout = 10
+
+// RenamePrefix renames the tables from the old prefix to the new one and makes the new
+// prefix the one of the instance, the indexes named after their table are renamed along
+// the tables are renamed in a single statement on mysql and in a transaction on the
+// other databases so either all or none of them are renamed, the missing tables are skipped
+// the indexes are renamed in the same transaction, on mysql the indexes and the tables
+// are renamed back if an index cannot be renamed
+// the queries of the instance wait for the rename and use the new tables once it's done
+// it returns ErrPrefixConflict if a table with the new prefix already exists and
+// ErrInvalidOptions if the old prefix is not the one of the instance or has no tables
+// the other instances using the tables keep the old prefix, they must be restarted
+// with the new one once the rename is done
+func (a *Authority) RenamePrefix(oldPrefix string, newPrefix string) error {
+	if err := a.checkMutation(context.Background(), Mutation{Operation: OpRenamePrefix, Name: newPrefix}); err != nil {
+		return err
+	}
+	if newPrefix == "" {
+		return wrapError(ErrInvalidOptions, errors.New("the tables prefix is required"))
+	}
+	if prefix := a.tables.getPrefix(); oldPrefix != prefix {
+		return wrapError(ErrInvalidOptions, fmt.Errorf("the tables prefix of the instance is %s", prefix))
+	}
+	if oldPrefix == newPrefix {
+		return nil
+	}
+
+	renames, err := a.tableRenames(oldPrefix, newPrefix)
+	if err != nil {
+		return err
+	}
+	if len(renames) == 0 {
+		return wrapError(ErrInvalidOptions, fmt.Errorf("no table with the prefix %s", oldPrefix))
+	}
+
+	// the tables are named under the lock so no query uses a table being renamed,
+	// the table names of the models must not be resolved while it's held
+	err = func() error {
+		a.tables.mu.Lock()
+		defer a.tables.mu.Unlock()
+		// another rename of the instance may have been done meanwhile
+		if a.tables.prefix != oldPrefix {
+			return wrapError(ErrInvalidOptions, fmt.Errorf("the tables prefix of the instance is %s", a.tables.prefix))
+		}
+		if err := a.renameTables(renames); err != nil {
+			return storeError(err)
+		}
+		a.tables.prefix = newPrefix
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	a.invalidate(uuid.Nil)
+
+	return nil
+}
+
+// renameTables renames the tables along with their indexes, all of them or none
+func (a *Authority) renameTables(renames []tableRename) error {
+	switch a.DB.Dialector.Name() {
+	case "mysql":
+		return a.DB.Connection(func(conn *gorm.DB) error {
+			var timeout int
+			if err := conn.Raw("SELECT @@SESSION.lock_wait_timeout").Scan(&timeout).Error; err != nil {
+				return err
+			}
+			if err := conn.Exec("SET SESSION lock_wait_timeout = ?", renameLockTimeout).Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SET SESSION lock_wait_timeout = ?", timeout)
+
+			return renameMySQLTables(conn, renames)
+		})
+
+	case "postgres":
+		return a.DB.Transaction(func(tx *gorm.DB) error {
+			for _, r := range renames {
+				if err := tx.Migrator().RenameTable(r.from, r.to); err != nil {
+					return err
+				}
+				for _, idx := range r.indexes {
+					if err := tx.Exec(fmt.Sprintf(`ALTER INDEX "%s" RENAME TO "%s"`, idx.from, idx.to)).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	}
+
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		for _, r := range renames {
+			if err := tx.Migrator().RenameTable(r.from, r.to); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// renameMySQLTables renames the tables in a single statement then their indexes,
+// the ddl statements of mysql are not transactional so the indexes already renamed
+// and the tables are renamed back if an index cannot be renamed
+func renameMySQLTables(conn *gorm.DB, renames []tableRename) error {
+	var pairs, back []string
+	for _, r := range renames {
+		pairs = append(pairs, fmt.Sprintf("`%s` TO `%s`", r.from, r.to))
+		back = append(back, fmt.Sprintf("`%s` TO `%s`", r.to, r.from))
+	}
+	if err := conn.Exec("RENAME TABLE " + strings.Join(pairs, ", ")).Error; err != nil {
+		return err
+	}
+
+	var renamed []string
+	for _, r := range renames {
+		for _, idx := range r.indexes {
+			if err := conn.Exec(mysqlRenameIndex(r.to, idx, idx.from, idx.to)).Error; err != nil {
+				for i := len(renamed) - 1; i >= 0; i-- {
+					conn.Exec(renamed[i])
+				}
+				conn.Exec("RENAME TABLE " + strings.Join(back, ", "))
+				return err
+			}
+			renamed = append(renamed, mysqlRenameIndex(r.to, idx, idx.to, idx.from))
+		}
+	}
+
+	return nil
+}
+
+// tableRenames returns the tables of the models existing with the old prefix
+// it returns ErrPrefixConflict if a table with the new prefix already exists
+func (a *Authority) tableRenames(oldPrefix string, newPrefix string) ([]tableRename, error) {
+	var renames []tableRename
+	migrator := a.DB.Migrator()
+	for _, m := range models() {
+		r := tableRename{from: oldPrefix + m.table(), to: newPrefix + m.table()}
+		if !migrator.HasTable(r.from) {
+			continue
+		}
+		if migrator.HasTable(r.to) {
+			return nil, wrapError(ErrPrefixConflict, fmt.Errorf("table %s", r.to))
+		}
+
+		// a fresh cache as the schemas of the db are cached with the table names of the instance
+		s, err := schema.ParseWithSpecialTableName(m, &sync.Map{}, a.DB.NamingStrategy, r.from)
+		if err != nil {
+			return nil, err
+		}
+		for _, idx := range s.ParseIndexes() {
+			if len(idx.Fields) != 1 || idx.Name != a.DB.NamingStrategy.IndexName(s.Table, idx.Fields[0].Name) {
+				continue
+			}
+			field := idx.Fields[0]
+			r.indexes = append(r.indexes, indexRename{
+				from:   a.DB.NamingStrategy.IndexName(r.from, field.Name),
+				to:     a.DB.NamingStrategy.IndexName(r.to, field.Name),
+				column: field.DBName,
+				unique: idx.Class == "UNIQUE",
+			})
+		}
+		renames = append(renames, r)
+	}
+
+	return renames, nil
+}
@@ -0,0 +1,116 @@
+package authority_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestRenamePrefix(t *testing.T) {
+	defer authority.New(authority.Options{TablesPrefix: "authority_", DB: db})
+
+	auth := authority.New(authority.Options{
+		TablesPrefix: "rename_",
+		DB:           db,
+	})
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	// the queries made during the rename use the old or the new tables
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := auth.CheckRole(userID, "role-a"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	if err := auth.RenamePrefix("rename_", "renamed_"); err != nil {
+		t.Fatal("unexpected error while renaming the prefix.", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error("unexpected error while checking the role during the rename.", err)
+	}
+
+	if !db.Migrator().HasTable("renamed_roles") || db.Migrator().HasTable("rename_roles") {
+		t.Error("expecting the tables to be renamed")
+	}
+	if !db.Migrator().HasTable("renamed_assignment_events") {
+		t.Error("expecting all the tables to be renamed")
+	}
+	var indexes int64
+	db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_name = ? AND index_name = ? AND non_unique = 0",
+		"renamed_roles", "idx_renamed_roles_name").Scan(&indexes)
+	if indexes == 0 {
+		t.Error("expecting the unique index to be renamed")
+	}
+	if (authority.Role{}).TableName() != "renamed_roles" {
+		t.Error("expecting the new prefix to be used")
+	}
+
+	// the instance keeps working on the renamed tables
+	ok, err := auth.CheckPermission(userID, "permission-a")
+	if err != nil || !ok {
+		t.Error("expecting the permission to be granted after the rename.", err)
+	}
+	other := uuid.New()
+	if err := auth.AssignRole(other, "role-a"); err != nil {
+		t.Error("unexpected error while assigning a role after the rename.", err)
+	}
+	var count int64
+	db.Table("renamed_user_roles").Where("user_id = ?", other).Count(&count)
+	if count != 1 {
+		t.Error("expecting the assignment to be stored in the renamed tables")
+	}
+
+	// nothing is renamed if a table with the new prefix exists
+	db.Exec("CREATE TABLE taken_permissions (id int primary key)")
+	err = auth.RenamePrefix("renamed_", "taken_")
+	if !errors.Is(err, authority.ErrPrefixConflict) {
+		t.Error("expecting an error when a table with the new prefix exists")
+	}
+	if !db.Migrator().HasTable("renamed_roles") || (authority.Role{}).TableName() != "renamed_roles" {
+		t.Error("expecting nothing to be renamed")
+	}
+	ok, _ = auth.CheckRole(other, "role-a")
+	if !ok {
+		t.Error("expecting the instance to keep its tables after a conflict")
+	}
+
+	// the old prefix must be the one of the instance and have tables
+	err = auth.RenamePrefix("rename_", "other_")
+	if !errors.Is(err, authority.ErrInvalidOptions) {
+		t.Error("expecting an error when the old prefix is not the one of the instance")
+	}
+	empty := authority.New(authority.Options{
+		TablesPrefix: "empty_",
+		DB:           db,
+	})
+	dropTables := func(pattern string) {
+		var tables []string
+		db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name LIKE ?", pattern).Scan(&tables)
+		for _, table := range tables {
+			db.Migrator().DropTable(table)
+		}
+	}
+	dropTables("empty\\_%")
+	err = empty.RenamePrefix("empty_", "other_")
+	if !errors.Is(err, authority.ErrInvalidOptions) || (authority.Role{}).TableName() != "empty_roles" {
+		t.Error("expecting an error and the prefix to be kept when no table has the old prefix")
+	}
+
+	// clean up
+	dropTables("renamed\\_%")
+	db.Migrator().DropTable("taken_permissions")
+}
@@ -0,0 +1,186 @@
+package authority
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// repairBatchSize is the number of users repaired per transaction by RepairAllAssignments
+const repairBatchSize = 500
+
+// RepairAssignments checks the role assignments of the user and deletes the inconsistent
+// ones, the assignments of deleted roles and the duplicated assignments left by imports
+// or bugs are removed, the first assignment of a duplicated role is kept
+// it returns the number of assignments removed and an error if they could not be removed
+func (a *Authority) RepairAssignments(userID uuid.UUID) (int, error) {
+	return a.RepairAssignmentsContext(context.Background(), userID)
+}
+
+// RepairAssignmentsContext repairs the assignments of the user like RepairAssignments
+// the mutation hook receives the actor of the context
+func (a *Authority) RepairAssignmentsContext(ctx context.Context, userID uuid.UUID) (int, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRepairAssignments, UserID: userID}); err != nil {
+		return 0, err
+	}
+
+	var removed int
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		removed, err = a.repairUsers(tx, []uuid.UUID{userID})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if removed > 0 {
+		a.invalidate(userID)
+	}
+
+	return removed, nil
+}
+
+// RepairAllAssignments starts a background job repairing the assignments of all users
+// like RepairAssignments, the number of assignments removed is reported as Repaired
+// the users are repaired in batches ordered by id, the last repaired id is saved after every
+// batch so an interrupted job can be resumed with ResumeRepair
+// canceling the context stops the job before the next batch
+func (a *Authority) RepairAllAssignments(ctx context.Context) (*Job, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRepairAssignments}); err != nil {
+		return nil, err
+	}
+
+	state := JobState{Kind: jobKindRepair, Status: JobRunning}
+	if res := a.DB.Create(&state); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	return a.runRepairJob(ctx, state), nil
+}
+
+// ResumeRepair resumes an interrupted repair job from its last checkpoint
+// it returns ErrJobNotFound if the job is not present in the database
+// it returns ErrJobNotResumable if the job is not a repair job or is already completed
+func (a *Authority) ResumeRepair(ctx context.Context, jobID uint) (*Job, error) {
+	if err := a.checkMutation(ctx, Mutation{Operation: OpRepairAssignments}); err != nil {
+		return nil, err
+	}
+
+	state, err := a.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Kind != jobKindRepair || state.Status == JobCompleted {
+		return nil, ErrJobNotResumable
+	}
+
+	state.Status = JobRunning
+	state.Error = ""
+	if res := a.DB.Save(&state); res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	return a.runRepairJob(ctx, state), nil
+}
+
+func (a *Authority) runRepairJob(ctx context.Context, state JobState) *Job {
+	return a.runJob(ctx, state, func(ctx context.Context, state *JobState, save func() error) (*ImportReport, error) {
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			userIDs, err := a.nextRepairUsers(state.Cursor)
+			if err != nil {
+				return nil, err
+			}
+			if len(userIDs) == 0 {
+				return nil, nil
+			}
+
+			var removed int
+			err = a.DB.Transaction(func(tx *gorm.DB) error {
+				var err error
+				removed, err = a.repairUsers(tx, userIDs)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			if removed > 0 {
+				a.invalidate(uuid.Nil)
+			}
+
+			state.Cursor = userIDs[len(userIDs)-1].String()
+			state.Processed += int64(len(userIDs))
+			state.Repaired += int64(removed)
+			if err := save(); err != nil {
+				return nil, err
+			}
+		}
+	})
+}
+
+// nextRepairUsers returns the next batch of users having roles after the cursor, ordered by id
+func (a *Authority) nextRepairUsers(cursor string) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	res := a.DB.Model(&UserRole{}).Distinct("user_id").Where("user_id > ?", cursor).
+		Order("user_id").Limit(repairBatchSize).Pluck("user_id", &userIDs)
+	if res.Error != nil {
+		return nil, storeError(res.Error)
+	}
+
+	return userIDs, nil
+}
+
+// repairUsers removes the user roles of deleted roles and the duplicated user roles
+// of the users, the first assignment of a duplicated role is kept
+// it returns the number of rows removed
+func (a *Authority) repairUsers(tx *gorm.DB, userIDs []uuid.UUID) (int, error) {
+	var userRoles []UserRole
+	if res := tx.Where("user_id IN (?)", userIDs).Order("id").Find(&userRoles); res.Error != nil {
+		return 0, storeError(res.Error)
+	}
+	if len(userRoles) == 0 {
+		return 0, nil
+	}
+
+	var roleIDs []uint
+	for _, ur := range userRoles {
+		roleIDs = append(roleIDs, ur.RoleID)
+	}
+	var existing []uint
+	if res := tx.Model(&Role{}).Where("id IN (?)", roleIDs).Pluck("id", &existing); res.Error != nil {
+		return 0, storeError(res.Error)
+	}
+	roles := map[uint]bool{}
+	for _, id := range existing {
+		roles[id] = true
+	}
+
+	type assignment struct {
+		userID   uuid.UUID
+		tenantID string
+		roleID   uint
+	}
+	seen := map[assignment]bool{}
+	var removed []UserRole
+	for _, ur := range userRoles {
+		key := assignment{ur.UserID, ur.TenantID, ur.RoleID}
+		if !roles[ur.RoleID] || seen[key] {
+			removed = append(removed, ur)
+			continue
+		}
+		seen[key] = true
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+
+	res := userRolesOf(tx, removed).Delete(UserRole{})
+	if res.Error != nil {
+		return 0, storeError(res.Error)
+	}
+
+	return int(res.RowsAffected), nil
+}
@@ -0,0 +1,130 @@
+package authority_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestRepairAssignments(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	// consistent assignments are kept
+	removed, err := auth.RepairAssignments(userID)
+	if err != nil {
+		t.Error("unexpected error while repairing the assignments.", err)
+	}
+	if removed != 0 {
+		t.Error("expecting nothing to be removed from consistent assignments")
+	}
+
+	// the duplicated assignments and the assignments of deleted roles are removed
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Create(&authority.UserRole{UserID: userID, RoleID: r.ID})
+	db.Create(&authority.UserRole{UserID: userID, RoleID: r.ID + 1000000})
+
+	// the repairs are passed to the mutation hook
+	auth.SetMutationHook(func(ctx context.Context, m authority.Mutation) error {
+		if m.Operation == authority.OpRepairAssignments && m.UserID == userID {
+			return errors.New("repairs are disabled")
+		}
+		return nil
+	})
+	_, err = auth.RepairAssignments(userID)
+	if !errors.Is(err, authority.ErrMutationRejected) {
+		t.Error("expecting the hook to reject the repair.", err)
+	}
+	auth.SetMutationHook(nil)
+
+	removed, err = auth.RepairAssignments(userID)
+	if err != nil {
+		t.Error("unexpected error while repairing the assignments.", err)
+	}
+	if removed != 2 {
+		t.Errorf("expecting the 2 inconsistent rows to be removed, got %d", removed)
+	}
+	var count int64
+	db.Model(&authority.UserRole{}).Where("user_id = ?", userID).Count(&count)
+	if count != 1 {
+		t.Error("expecting the first assignment to be kept")
+	}
+	ok, _ := auth.CheckPermission(userID, "permission-a")
+	if !ok {
+		t.Error("expecting the permission to be kept after the repair")
+	}
+
+	// clean up
+	db.Where("user_id = ?", userID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name = ?", "permission-a").Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
+
+func TestRepairAllAssignments(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		auth.AssignRole(id, "role-a")
+	}
+	orphan := uuid.New()
+	ids = append(ids, orphan)
+	db.Create(&authority.UserRole{UserID: orphan, RoleID: r.ID + 1000000})
+
+	job, err := auth.RepairAllAssignments(context.Background())
+	if err != nil {
+		t.Error("unexpected error while starting repair job.", err)
+	}
+	report, err := job.Wait()
+	if err != nil {
+		t.Error("unexpected error while running repair job.", err)
+	}
+	if report != nil {
+		t.Error("expecting no import report for a repair job")
+	}
+	state, _ := auth.GetJob(job.ID())
+	if state.Status != authority.JobCompleted || state.Processed < 4 || state.Repaired < 1 {
+		t.Errorf("unexpected job state %+v", state)
+	}
+	var count int64
+	db.Model(&authority.UserRole{}).Where("user_id IN (?)", ids).Count(&count)
+	if count != 3 {
+		t.Error("expecting the consistent assignments to be kept")
+	}
+	db.Model(&authority.UserRole{}).Where("user_id = ?", orphan).Count(&count)
+	if count != 0 {
+		t.Error("expecting the assignment of the deleted role to be removed")
+	}
+
+	// completed jobs cannot be resumed
+	_, err = auth.ResumeRepair(context.Background(), job.ID())
+	if !errors.Is(err, authority.ErrJobNotResumable) {
+		t.Error("expecting an error when resuming a completed job")
+	}
+
+	// clean up
+	db.Where("id = ?", job.ID()).Delete(authority.JobState{})
+	db.Where("user_id IN (?)", ids).Delete(authority.UserRole{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
@@ -0,0 +1,289 @@
+package authority
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errReplayAction = errors.New("unknown event action")
+
+var errEventNames = errors.New("event without the names of its role or permission")
+
+// ChangeEvent is an assignment event as exported by ExportEvents, the roles and
+// permissions are referenced by name so the events can be replayed in another environment
+type ChangeEvent struct {
+	Action     string    `json:"action"`
+	UserID     uuid.UUID `json:"user_id"`
+	Tenant     string    `json:"tenant,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	Permission string    `json:"permission,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	TicketRef  string    `json:"ticket_ref,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// ReplayReport summarizes an events replay
+type ReplayReport struct {
+	// Events is the number of events read
+	Events int
+	// Applied is the number of events that changed the state
+	Applied int
+	// Skipped is the number of events without effect, like assigning an assigned role
+	// or revoking a role that is not assigned
+	Skipped int
+}
+
+// exportBatchSize is the number of events read per query by ExportEvents
+const exportBatchSize = 500
+
+// ExportEvents writes the assignment events recorded since the given time
+// as json lines in the order they were recorded, a zero time exports all the events
+// the events are read in batches so the export is not bound by the memory
+// the justifications of the assignments are exported decrypted
+// it returns an error if the events could not be read, decrypted or written
+// or if the names of the role or permission of an event cannot be resolved
+func (a *Authority) ExportEvents(w io.Writer, since time.Time) error {
+	roleNames := map[uint]string{}
+	permNames := map[uint]string{}
+	enc := json.NewEncoder(w)
+	var last uint
+	for {
+		var events []AssignmentEvent
+		res := a.DB.Where("created_at >= ?", since).Where("id > ?", last).Order("id").Limit(exportBatchSize).Find(&events)
+		if res.Error != nil {
+			return storeError(res.Error)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		if err := a.resolveNames(events, roleNames, permNames); err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			ev := ChangeEvent{Action: e.Action, UserID: e.UserID, Tenant: e.TenantID, Role: e.RoleName, Permission: e.PermissionName, At: e.CreatedAt}
+			if e.RoleID != 0 && ev.Role == "" {
+				ev.Role = roleNames[e.RoleID]
+			}
+			if e.PermissionID != 0 && ev.Permission == "" {
+				ev.Permission = permNames[e.PermissionID]
+			}
+			if !ev.resolved() {
+				return fmt.Errorf("event %d: %w", e.ID, errEventNames)
+			}
+			note, err := a.openNote(AssignmentNote{Reason: e.Reason, TicketRef: e.TicketRef})
+			if err != nil {
+				return err
+			}
+			ev.Reason, ev.TicketRef = note.Reason, note.TicketRef
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+		}
+		last = events[len(events)-1].ID
+	}
+}
+
+// resolveNames adds the names of the roles and permissions the events only carry the ids of
+// the names of the deleted roles and permissions are looked up in the earlier events
+func (a *Authority) resolveNames(events []AssignmentEvent, roleNames map[uint]string, permNames map[uint]string) error {
+	var roleIDs, permIDs []uint
+	for _, e := range events {
+		if _, ok := roleNames[e.RoleID]; e.RoleID != 0 && e.RoleName == "" && !ok {
+			roleIDs = append(roleIDs, e.RoleID)
+		}
+		if _, ok := permNames[e.PermissionID]; e.PermissionID != 0 && e.PermissionName == "" && !ok {
+			permIDs = append(permIDs, e.PermissionID)
+		}
+	}
+
+	if len(roleIDs) > 0 {
+		var roles []Role
+		if res := a.DB.Where("id IN (?)", roleIDs).Find(&roles); res.Error != nil {
+			return storeError(res.Error)
+		}
+		for _, r := range roles {
+			roleNames[r.ID] = r.Name
+		}
+		var deleted []uint
+		for _, id := range roleIDs {
+			if _, ok := roleNames[id]; !ok {
+				deleted = append(deleted, id)
+			}
+		}
+		if len(deleted) > 0 {
+			var named []AssignmentEvent
+			res := a.DB.Select("role_id, MAX(role_name) AS role_name").Where("role_id IN (?)", deleted).Where("role_name <> ''").Group("role_id").Find(&named)
+			if res.Error != nil {
+				return storeError(res.Error)
+			}
+			for _, e := range named {
+				roleNames[e.RoleID] = e.RoleName
+			}
+		}
+	}
+	if len(permIDs) > 0 {
+		var perms []Permission
+		if res := a.DB.Where("id IN (?)", permIDs).Find(&perms); res.Error != nil {
+			return storeError(res.Error)
+		}
+		for _, p := range perms {
+			permNames[p.ID] = p.Name
+		}
+		var deleted []uint
+		for _, id := range permIDs {
+			if _, ok := permNames[id]; !ok {
+				deleted = append(deleted, id)
+			}
+		}
+		if len(deleted) > 0 {
+			var named []AssignmentEvent
+			res := a.DB.Select("permission_id, MAX(permission_name) AS permission_name").Where("permission_id IN (?)", deleted).Where("permission_name <> ''").Group("permission_id").Find(&named)
+			if res.Error != nil {
+				return storeError(res.Error)
+			}
+			for _, e := range named {
+				permNames[e.PermissionID] = e.PermissionName
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReplayEvents applies the json lines of previously exported events in order
+// to reconstruct the assignments in another environment, the roles and permissions
+// missing are created without description
+// the events recorded by the replay keep the time of the replayed events
+// the events without effect are skipped so a replay can be run again after a failure
+// it returns an error with the line of the event that could not be applied
+// or that misses the names of its role or permission
+func (a *Authority) ReplayEvents(r io.Reader) (*ReplayReport, error) {
+	report := &ReplayReport{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var ev ChangeEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return report, fmt.Errorf("line %d: %w", line, err)
+		}
+		report.Events++
+
+		if !ev.resolved() {
+			return report, fmt.Errorf("line %d: %w", line, errEventNames)
+		}
+		applied, err := a.replayEvent(ev)
+		if err != nil {
+			return report, fmt.Errorf("line %d: %w", line, err)
+		}
+		if applied {
+			report.Applied++
+		} else {
+			report.Skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// resolved reports whether the event carries the names its action needs
+func (ev ChangeEvent) resolved() bool {
+	switch ev.Action {
+	case EventRoleAssigned, EventRoleRevoked, EventRoleDeleted:
+		return ev.Role != ""
+	case EventPermissionAssigned, EventPermissionRevoked:
+		return ev.Role != "" && ev.Permission != ""
+	case EventPermissionDeleted:
+		return ev.Permission != ""
+	}
+
+	return true
+}
+
+// replayEvent applies an event, it reports whether the event changed the state
+// the events recorded by the replay are given the time of the replayed event
+func (a *Authority) replayEvent(ev ChangeEvent) (bool, error) {
+	ctx := withEventTime(WithTenant(context.Background(), ev.Tenant), ev.At)
+	note := []AssignOption{WithReason(ev.Reason), WithTicketRef(ev.TicketRef)}
+	switch ev.Action {
+	case EventRoleAssigned:
+		if err := a.CreateRoleContext(ctx, ev.Role, ""); err != nil {
+			return false, err
+		}
+		err := a.AssignRoleContext(ctx, ev.UserID, ev.Role, note...)
+		if errors.Is(err, ErrRoleAlreadyAssigned) {
+			return false, nil
+		}
+		return err == nil, err
+
+	case EventRoleRevoked:
+		_, err := a.RevokeRoleContext(ctx, ev.UserID, ev.Role)
+		if errors.Is(err, ErrNothingToRevoke) || errors.Is(err, ErrRoleNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+
+	case EventPermissionAssigned:
+		if err := a.CreateRoleContext(ctx, ev.Role, ""); err != nil {
+			return false, err
+		}
+		if err := a.CreatePermissionContext(ctx, ev.Permission, ""); err != nil {
+			return false, err
+		}
+		role, err := a.findRole(ev.Role)
+		if err != nil {
+			return false, err
+		}
+		perm, err := a.findPermission(ev.Permission)
+		if err != nil {
+			return false, err
+		}
+		var count int64
+		if res := a.DB.Model(&RolePermission{}).Where("role_id = ?", role.ID).Where("permission_id = ?", perm.ID).Count(&count); res.Error != nil {
+			return false, storeError(res.Error)
+		}
+		if count > 0 {
+			return false, nil
+		}
+		err = a.AssignPermissionsContext(ctx, ev.Role, []string{ev.Permission}, note...)
+		return err == nil, err
+
+	case EventPermissionRevoked:
+		_, err := a.RevokeRolePermissionContext(ctx, ev.Role, ev.Permission)
+		if errors.Is(err, ErrNothingToRevoke) || errors.Is(err, ErrRoleNotFound) || errors.Is(err, ErrPermissionNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+
+	case EventRoleDeleted:
+		_, err := a.ForceDeleteRole(ctx, ev.Role)
+		if errors.Is(err, ErrRoleNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+
+	case EventPermissionDeleted:
+		_, err := a.ForceDeletePermission(ctx, ev.Permission)
+		if errors.Is(err, ErrPermissionNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	return false, fmt.Errorf("%w %q", errReplayAction, ev.Action)
+}
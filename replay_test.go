@@ -0,0 +1,198 @@
+package authority_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestReplayEvents(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	since := time.Now().Truncate(time.Millisecond)
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	first, second := uuid.New(), uuid.New()
+	tenant := authority.WithTenant(context.Background(), "tenant-a")
+	auth.AssignRole(first, "role-a")
+	auth.AssignRoleContext(tenant, second, "role-a")
+	auth.RevokeRole(first, "role-a")
+
+	var buf bytes.Buffer
+	if err := auth.ExportEvents(&buf, since); err != nil {
+		t.Error("unexpected error while exporting the events.", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 4 {
+		t.Errorf("expecting 4 exported events, got %d", lines)
+	}
+	exported := buf.String()
+
+	// replay in a wiped environment
+	auth.ForceDeleteRole(context.Background(), "role-a")
+	auth.ForceDeletePermission(context.Background(), "permission-a")
+	report, err := auth.ReplayEvents(strings.NewReader(exported))
+	if err != nil {
+		t.Error("unexpected error while replaying the events.", err)
+	}
+	if report.Events != 4 || report.Applied != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	ok, _ := auth.CheckPermissionContext(tenant, second, "permission-a")
+	if !ok {
+		t.Error("expecting the tenant assignment to be replayed")
+	}
+	ok, _ = auth.CheckRole(first, "role-a")
+	if ok {
+		t.Error("expecting the revoke to be replayed")
+	}
+
+	// replaying again leads to the same state
+	_, err = auth.ReplayEvents(strings.NewReader(exported))
+	if err != nil {
+		t.Error("unexpected error while replaying the events again.", err)
+	}
+	roles, _ := auth.GetUserRolesContext(tenant, second)
+	if len(roles) != 1 {
+		t.Error("expecting the assignment not to be duplicated")
+	}
+	ok, _ = auth.CheckRole(first, "role-a")
+	if ok {
+		t.Error("expecting the revoke to be replayed again")
+	}
+
+	_, err = auth.ReplayEvents(strings.NewReader("{\"action\":\"role_created\"}\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Error("expecting an error with the line of the unknown event")
+	}
+
+	// clean up
+	auth.ForceDeleteRole(context.Background(), "role-a")
+	auth.ForceDeletePermission(context.Background(), "permission-a")
+	db.Where("user_id IN (?)", []uuid.UUID{first, second}).Delete(authority.AssignmentEvent{})
+}
+
+func TestReplayEventsNames(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	since := time.Now().Truncate(time.Millisecond)
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	auth.ForceDeletePermission(context.Background(), "permission-a")
+
+	// the revoke of a deleted permission carries the name of its role
+	var buf bytes.Buffer
+	if err := auth.ExportEvents(&buf, since); err != nil {
+		t.Error("unexpected error while exporting the events.", err)
+	}
+	if !strings.Contains(buf.String(), `"action":"permission_revoked","user_id":"00000000-0000-0000-0000-000000000000","role":"role-a","permission":"permission-a"`) {
+		t.Errorf("expecting the revoke to be exported with its names, got %s", buf.String())
+	}
+
+	// the events without names fail the export and the replay
+	orphan := authority.AssignmentEvent{Action: authority.EventRoleAssigned, UserID: uuid.New(), RoleID: 1000000000}
+	db.Create(&orphan)
+	if err := auth.ExportEvents(&bytes.Buffer{}, since); err == nil {
+		t.Error("expecting an error when exporting an event without names")
+	}
+	db.Where("id = ?", orphan.ID).Delete(authority.AssignmentEvent{})
+	report, err := auth.ReplayEvents(strings.NewReader(`{"action":"role_assigned","user_id":"` + uuid.NewString() + `"}` + "\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 1") || report.Skipped != 0 {
+		t.Error("expecting an error with the line of the event without names")
+	}
+
+	// the replayed events keep their time
+	userID := uuid.New()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err = auth.ReplayEvents(strings.NewReader(`{"action":"role_assigned","user_id":"` + userID.String() + `","role":"role-a","at":"2020-01-02T03:04:05Z"}` + "\n"))
+	if err != nil {
+		t.Error("unexpected error while replaying the event.", err)
+	}
+	roles, _ := auth.GetUserRolesAt(userID, at.Add(time.Hour))
+	if len(roles) != 1 || roles[0] != "role-a" {
+		t.Errorf("expecting the role to be assigned at the time of the replayed event, got %v", roles)
+	}
+	roles, _ = auth.GetUserRolesAt(userID, at.Add(-time.Hour))
+	if len(roles) != 0 {
+		t.Error("expecting the role not to be assigned before the replayed event")
+	}
+
+	// the events recorded meanwhile by other writers keep their time
+	late := uuid.New()
+	written := false
+	auth.SetMutationHook(func(ctx context.Context, m authority.Mutation) error {
+		if m.Operation == authority.OpAssignRole && !written {
+			written = true
+			auth.AssignRoleContext(authority.WithTenant(context.Background(), "tenant-x"), late, "role-a")
+		}
+		return nil
+	})
+	_, err = auth.ReplayEvents(strings.NewReader(`{"action":"role_assigned","user_id":"` + late.String() + `","role":"role-a","at":"2020-01-02T03:04:05Z"}` + "\n"))
+	auth.SetMutationHook(nil)
+	if err != nil {
+		t.Error("unexpected error while replaying the event.", err)
+	}
+	var concurrent authority.AssignmentEvent
+	db.Where("user_id = ?", late).Where("tenant_id = ?", "tenant-x").First(&concurrent)
+	if !concurrent.CreatedAt.After(at.Add(time.Hour)) {
+		t.Errorf("expecting the concurrent event to keep its time, got %v", concurrent.CreatedAt)
+	}
+
+	// clean up
+	auth.ForceDeleteRole(context.Background(), "role-a")
+	db.Where("user_id IN (?)", []uuid.UUID{userID, late}).Delete(authority.AssignmentEvent{})
+	db.Where("role_name = ?", "role-a").Delete(authority.AssignmentEvent{})
+}
+
+func TestExportEventsBatches(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	// more events than a batch are exported in order
+	since := time.Now().Truncate(time.Millisecond)
+	userID := uuid.New()
+	var events []authority.AssignmentEvent
+	for i := 0; i < 501; i++ {
+		action := authority.EventRoleAssigned
+		if i%2 == 1 {
+			action = authority.EventRoleRevoked
+		}
+		events = append(events, authority.AssignmentEvent{Action: action, UserID: userID, RoleName: "role-a"})
+	}
+	db.CreateInBatches(&events, 100)
+
+	var buf bytes.Buffer
+	if err := auth.ExportEvents(&buf, since); err != nil {
+		t.Error("unexpected error while exporting the events.", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 501 {
+		t.Errorf("expecting 501 exported events, got %d", len(lines))
+	}
+	for i, line := range lines {
+		action := authority.EventRoleAssigned
+		if i%2 == 1 {
+			action = authority.EventRoleRevoked
+		}
+		if !strings.Contains(line, `"action":"`+action+`"`) {
+			t.Fatalf("expecting the events to be exported in order, got %s at %d", line, i)
+		}
+	}
+
+	// clean up
+	db.Where("user_id = ?", userID).Delete(authority.AssignmentEvent{})
+}
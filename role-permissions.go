@@ -5,9 +5,17 @@ type RolePermission struct {
 	ID           uint
 	RoleID       uint
 	PermissionID uint
+	// Reason and TicketRef justify the assignment, they are encrypted if an encryptor is set
+	Reason    string
+	TicketRef string
 }
 
 // TableName sets the table name
 func (r RolePermission) TableName() string {
-	return tablePrefix + "role_permissions"
+	return defaultPrefix() + r.table()
+}
+
+// table returns the table name without the prefix
+func (RolePermission) table() string {
+	return "role_permissions"
 }
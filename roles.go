@@ -1,13 +1,32 @@
 package authority
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
 // Role represents the database model of roles
 type Role struct {
 	ID          uint
-	Name        string
+	Name        string `gorm:"size:191;uniqueIndex"`
 	Description string
+	// OwnerID is the user who owns the role, the owner can administer the role
+	OwnerID uuid.UUID
+	// ManagedByRoleID is the role whose holders can administer the role
+	ManagedByRoleID uint
+	// Module is the module that installed the role, empty for the application roles
+	Module    string `gorm:"size:191;not null;default:''"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // TableName sets the table name
 func (r Role) TableName() string {
-	return tablePrefix + "roles"
+	return defaultPrefix() + r.table()
+}
+
+// table returns the table name without the prefix
+func (Role) table() string {
+	return "roles"
 }
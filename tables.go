@@ -0,0 +1,115 @@
+package authority
+
+import (
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// tablesSetting is the gorm setting holding the tables of the instance of a statement
+const tablesSetting = "authority:tables"
+
+// tablesCallback is the name of the gorm callback naming the tables of the statements
+const tablesCallback = "authority:tables"
+
+// model is a model of the package stored in a prefixed table
+type model interface {
+	// table returns the table name without the prefix
+	table() string
+}
+
+// modelTables maps the model types to their table names without the prefix
+var modelTables = map[reflect.Type]string{}
+
+func init() {
+	for _, m := range models() {
+		modelTables[reflect.TypeOf(m).Elem()] = m.table()
+	}
+}
+
+// tables holds the tables prefix of an instance
+type tables struct {
+	mu     sync.RWMutex
+	prefix string
+}
+
+// name returns the table name of the model with the prefix
+func (t *tables) name(m model) string {
+	return t.getPrefix() + m.table()
+}
+
+func (t *tables) getPrefix() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.prefix
+}
+
+// defaultMu guards the default instance returned by Resolve()
+var defaultMu sync.RWMutex
+
+// setDefault makes the instance the one returned by Resolve()
+func setDefault(a *Authority) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	auth = a
+}
+
+// defaultPrefix returns the tables prefix of the default instance, it's the prefix
+// of the TableName methods and of the queries made without an instance
+func defaultPrefix() string {
+	defaultMu.RLock()
+	a := auth
+	defaultMu.RUnlock()
+	if a == nil || a.tables == nil {
+		return ""
+	}
+
+	return a.tables.getPrefix()
+}
+
+// tableName returns the table name of the model with the prefix of the instance
+func (a *Authority) tableName(m model) string {
+	if a.tables == nil {
+		return defaultPrefix() + m.table()
+	}
+
+	return a.tables.name(m)
+}
+
+var registerMu sync.Mutex
+
+// scopeTables returns the db naming the tables with the prefix of the instance
+// the prefix is carried by the statements so the instances sharing a db keep their own tables
+func scopeTables(db *gorm.DB, t *tables) *gorm.DB {
+	registerMu.Lock()
+	if db.Callback().Query().Get(tablesCallback) == nil {
+		db.Callback().Create().Before("*").Register(tablesCallback, nameTables)
+		db.Callback().Query().Before("*").Register(tablesCallback, nameTables)
+		db.Callback().Update().Before("*").Register(tablesCallback, nameTables)
+		db.Callback().Delete().Before("*").Register(tablesCallback, nameTables)
+		db.Callback().Row().Before("*").Register(tablesCallback, nameTables)
+	}
+	registerMu.Unlock()
+
+	return db.Set(tablesSetting, t).Session(&gorm.Session{})
+}
+
+// nameTables names the table of a statement on a model of the package with the prefix
+// of its instance, or the prefix of the default instance, the tables set explicitly are kept
+func nameTables(db *gorm.DB) {
+	s := db.Statement.Schema
+	if s == nil || db.Statement.Table != s.Table {
+		return
+	}
+	table, ok := modelTables[s.ModelType]
+	if !ok {
+		return
+	}
+
+	if t, ok := db.Get(tablesSetting); ok {
+		db.Statement.Table = t.(*tables).getPrefix() + table
+		return
+	}
+	db.Statement.Table = defaultPrefix() + table
+}
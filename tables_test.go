@@ -0,0 +1,69 @@
+package authority_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestInstancesPrefix(t *testing.T) {
+	defer authority.New(authority.Options{TablesPrefix: "authority_", DB: db})
+
+	// two instances sharing the db with their own tables
+	billing := authority.New(authority.Options{TablesPrefix: "billing_", DB: db})
+	shipping := authority.New(authority.Options{TablesPrefix: "shipping_", DB: db})
+	authority.Register("billing", billing)
+	authority.Register("shipping", shipping)
+
+	authority.Resolve("billing").CreateRole("role-a", "a billing role")
+	authority.Resolve("billing").CreatePermission("invoices.view", "a billing permission")
+	authority.Resolve("billing").AssignPermissions("role-a", []string{"invoices.view"})
+	userID := uuid.New()
+	err := authority.Resolve("billing").AssignRole(userID, "role-a")
+	if err != nil {
+		t.Error("unexpected error while assigning role.", err)
+	}
+
+	ok, err := billing.CheckPermission(userID, "invoices.view")
+	if err != nil || !ok {
+		t.Error("expecting the permission to be granted by the billing instance.", err)
+	}
+	ok, _ = shipping.CheckRole(userID, "role-a")
+	if ok {
+		t.Error("expecting the shipping instance not to see the billing tables")
+	}
+	var count int64
+	db.Table("billing_user_roles").Where("user_id = ?", userID).Count(&count)
+	if count != 1 {
+		t.Error("expecting the assignment to be stored in the billing tables")
+	}
+	db.Table("shipping_roles").Count(&count)
+	if count != 0 {
+		t.Error("expecting nothing to be stored in the shipping tables")
+	}
+
+	// the default instance is the last one initiated
+	if authority.Resolve() != shipping || (authority.Role{}).TableName() != "shipping_roles" {
+		t.Error("expecting the last initiated instance to be the default one")
+	}
+
+	// a failing instance doesn't replace the default one
+	_, err = authority.NewWithError(authority.Options{TablesPrefix: strings.Repeat("x", 64) + "_", DB: db})
+	if err == nil {
+		t.Error("expecting an error when the tables cannot be migrated")
+	}
+	if authority.Resolve() != shipping {
+		t.Error("expecting the default instance to be kept after a failure")
+	}
+
+	// clean up
+	for _, prefix := range []string{"billing_", "shipping_"} {
+		var tables []string
+		db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name LIKE ?", prefix+"%").Scan(&tables)
+		for _, table := range tables {
+			db.Migrator().DropTable(table)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package authority
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+
+	"github.com/google/uuid"
+)
+
+// FuncMap returns the template funcs checking the user roles and permissions
+//
+//	{{if can .UserID "posts.edit"}}<button>Edit</button>{{end}}
+//	{{if hasRole .UserID "admin"}}...{{end}}
+//
+// the lookups are memoized for this render by the loader of the context, or a new one,
+// so the map is meant to be created for every render on a clone of the parsed template
+//
+//	t, _ := tmpl.Clone()
+//	t.Funcs(auth.FuncMap(r.Context())).Execute(w, data)
+//
+// the user id can be a uuid.UUID or its string form, a missing role or permission
+// stops the render with an error
+func (a *Authority) FuncMap(ctx context.Context) template.FuncMap {
+	l := LoaderFromContext(ctx)
+	if l == nil {
+		l = a.NewLoader(ctx)
+	}
+
+	return template.FuncMap{
+		"can": func(user interface{}, permName string) (bool, error) {
+			userID, err := templateUserID(user)
+			if err != nil {
+				return false, err
+			}
+			return l.CheckPermission(userID, permName)
+		},
+		"hasRole": func(user interface{}, roleName string) (bool, error) {
+			userID, err := templateUserID(user)
+			if err != nil {
+				return false, err
+			}
+			return l.CheckRole(userID, roleName)
+		},
+	}
+}
+
+// templateUserID returns the user id passed to a template func
+func templateUserID(user interface{}) (uuid.UUID, error) {
+	switch id := user.(type) {
+	case uuid.UUID:
+		return id, nil
+	case string:
+		return uuid.Parse(id)
+	}
+
+	return uuid.Nil, fmt.Errorf("unexpected user id of type %T", user)
+}
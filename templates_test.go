@@ -0,0 +1,52 @@
+package authority_test
+
+import (
+	"context"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestFuncMap(t *testing.T) {
+	auth := authority.New(authority.Options{
+		TablesPrefix: "authority_",
+		DB:           db,
+	})
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.CreatePermission("permission-b", "b description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	tmpl := template.Must(template.New("page").Funcs(auth.FuncMap(context.Background())).Parse(
+		`{{if can .UserID "permission-a"}}[edit]{{end}}{{if can .UserID "permission-b"}}[delete]{{end}}{{if hasRole .ID "role-a"}}[admin]{{end}}`))
+
+	var b strings.Builder
+	err := tmpl.Execute(&b, map[string]interface{}{"UserID": userID, "ID": userID.String()})
+	if err != nil {
+		t.Error("unexpected error while rendering template.", err)
+	}
+	if b.String() != "[edit][admin]" {
+		t.Errorf("unexpected render %q", b.String())
+	}
+
+	// a missing permission stops the render
+	tmpl = template.Must(template.New("page").Funcs(auth.FuncMap(context.Background())).Parse(`{{if can .UserID "permission-x"}}x{{end}}`))
+	err = tmpl.Execute(&b, map[string]interface{}{"UserID": userID})
+	if err == nil {
+		t.Error("expecting an error for a missing permission")
+	}
+
+	// clean up
+	var r authority.Role
+	db.Where("name = ?", "role-a").First(&r)
+	db.Where("role_id = ?", r.ID).Delete(authority.UserRole{})
+	db.Where("role_id = ?", r.ID).Delete(authority.RolePermission{})
+	db.Where("name IN (?)", []string{"permission-a", "permission-b"}).Delete(authority.Permission{})
+	db.Where("name = ?", "role-a").Delete(authority.Role{})
+}
@@ -0,0 +1,131 @@
+package authority
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// usageFlushSize is the number of counted checks written at once
+const usageFlushSize = 1000
+
+// usageCounters holds the check counters not written yet
+type usageCounters struct {
+	mu      sync.Mutex
+	pending map[string]*PermissionUsage
+	checks  int
+}
+
+// WithUsageTracking counts the checks of every permission
+func WithUsageTracking() Option {
+	return func(o *Options) {
+		o.TrackUsage = true
+	}
+}
+
+// GetPermissionUsage returns the check counters of the permission
+// summed across the instances, the counters not written yet by this instance are included
+// the counters are zero if the usage is not tracked
+func (a *Authority) GetPermissionUsage(permName string) (PermissionUsage, error) {
+	usage := PermissionUsage{Permission: permName}
+	res := a.DB.Model(&PermissionUsage{}).
+		Select("COALESCE(SUM(checks), 0) AS checks, COALESCE(SUM(allowed), 0) AS allowed, COALESCE(SUM(denied), 0) AS denied").
+		Where("permission = ?", permName).
+		Scan(&usage)
+	if res.Error != nil {
+		return usage, storeError(res.Error)
+	}
+
+	if a.usage != nil {
+		a.usage.mu.Lock()
+		if p, found := a.usage.pending[permName]; found {
+			usage.Checks += p.Checks
+			usage.Allowed += p.Allowed
+			usage.Denied += p.Denied
+		}
+		a.usage.mu.Unlock()
+	}
+
+	return usage, nil
+}
+
+// FlushUsage writes the check counters not written yet, it's meant to be called on shutdown
+// it returns an error if the counters could not be written
+func (a *Authority) FlushUsage() error {
+	if a.usage == nil {
+		return nil
+	}
+
+	return a.writeUsage(a.usage.take())
+}
+
+// countCheck counts a check of the permission if the usage is tracked
+// the counters are written in the background every usageFlushSize checks
+func (a *Authority) countCheck(permName string, allowed bool, err error) {
+	if a.usage == nil || err != nil {
+		return
+	}
+
+	u := a.usage
+	u.mu.Lock()
+	p, found := u.pending[permName]
+	if !found {
+		p = &PermissionUsage{Permission: permName}
+		u.pending[permName] = p
+	}
+	p.Checks++
+	if allowed {
+		p.Allowed++
+	} else {
+		p.Denied++
+	}
+	u.checks++
+	full := u.checks >= usageFlushSize
+	u.mu.Unlock()
+
+	if full {
+		go func(pending map[string]*PermissionUsage) {
+			if err := a.writeUsage(pending); err != nil {
+				a.logf("authority: permission usage not written: %v", err)
+			}
+		}(u.take())
+	}
+}
+
+// take returns the pending counters and resets them
+func (u *usageCounters) take() map[string]*PermissionUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	pending := u.pending
+	u.pending = map[string]*PermissionUsage{}
+	u.checks = 0
+
+	return pending
+}
+
+// writeUsage adds the counters to the stored ones in a transaction
+func (a *Authority) writeUsage(pending map[string]*PermissionUsage) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		for name, p := range pending {
+			res := tx.Model(&PermissionUsage{}).Where("permission = ?", name).Updates(map[string]interface{}{
+				"checks":  gorm.Expr("checks + ?", p.Checks),
+				"allowed": gorm.Expr("allowed + ?", p.Allowed),
+				"denied":  gorm.Expr("denied + ?", p.Denied),
+			})
+			if res.Error != nil {
+				return storeError(res.Error)
+			}
+			if res.RowsAffected > 0 {
+				continue
+			}
+			if res := tx.Create(&PermissionUsage{Permission: name, Checks: p.Checks, Allowed: p.Allowed, Denied: p.Denied}); res.Error != nil {
+				return storeError(res.Error)
+			}
+		}
+		return nil
+	})
+}
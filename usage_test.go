@@ -0,0 +1,62 @@
+package authority_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/faozimipa/authority"
+	"github.com/google/uuid"
+)
+
+func TestPermissionUsage(t *testing.T) {
+	auth, err := authority.NewWithOptions(
+		authority.WithDB(db),
+		authority.WithPrefix("authority_"),
+		authority.WithUsageTracking(),
+	)
+	if err != nil {
+		t.Fatal("unexpected error while initiating authority.", err)
+	}
+
+	auth.CreateRole("role-a", "a description role")
+	auth.CreatePermission("permission-a", "a description permission")
+	auth.AssignPermissions("role-a", []string{"permission-a"})
+	userID := uuid.New()
+	auth.AssignRole(userID, "role-a")
+
+	auth.CheckPermission(userID, "permission-a")
+	auth.CheckPermissionDecision(context.Background(), userID, "permission-a")
+	auth.NewLoader(context.Background()).CheckPermission(uuid.New(), "permission-a")
+	auth.CheckPermission(userID, "permission-x")
+
+	// the counters not written yet are included
+	usage, err := auth.GetPermissionUsage("permission-a")
+	if err != nil {
+		t.Error("unexpected error while getting the permission usage.", err)
+	}
+	if usage.Checks != 3 || usage.Allowed != 2 || usage.Denied != 1 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	usage, _ = auth.GetPermissionUsage("permission-x")
+	if usage.Checks != 0 {
+		t.Error("expecting the failed checks not to be counted")
+	}
+
+	// the written counters are summed
+	if err := auth.FlushUsage(); err != nil {
+		t.Error("unexpected error while flushing the permission usage.", err)
+	}
+	auth.CheckPermission(userID, "permission-a")
+	auth.FlushUsage()
+	usage, _ = authority.New(authority.Options{TablesPrefix: "authority_", DB: db}).GetPermissionUsage("permission-a")
+	if usage.Checks != 4 || usage.Allowed != 3 || usage.Denied != 1 {
+		t.Errorf("unexpected written usage %+v", usage)
+	}
+
+	// clean up
+	auth.RevokeRole(userID, "role-a")
+	auth.DeleteRole("role-a")
+	auth.DeletePermission("permission-a")
+	db.Where("user_id = ?", userID).Delete(authority.AssignmentEvent{})
+	db.Where("permission = ?", "permission-a").Delete(authority.PermissionUsage{})
+}
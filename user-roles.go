@@ -6,12 +6,23 @@ import (
 
 // UserRole represents the relationship between users and roles
 type UserRole struct {
-	ID     uint
-	UserID uuid.UUID
+	ID uint
+	// UserID has a fixed size so it can be part of the keys of the partitioned table
+	UserID uuid.UUID `gorm:"type:char(36);index"`
 	RoleID uint
+	// TenantID scopes the assignment to a tenant, empty for the global scope
+	TenantID string `gorm:"size:191;not null;default:''"`
+	// Reason and TicketRef justify the assignment, they are encrypted if an encryptor is set
+	Reason    string
+	TicketRef string
 }
 
 // TableName sets the table name
 func (u UserRole) TableName() string {
-	return tablePrefix + "user_roles"
+	return defaultPrefix() + u.table()
+}
+
+// table returns the table name without the prefix
+func (UserRole) table() string {
+	return "user_roles"
 }